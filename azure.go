@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// discoverAzureContext reads the Azure Pipelines predefined variables
+func discoverAzureContext(in *ActionInputs, getenv func(string) string) {
+	setDefault(&in.CommitSha, getenv("BUILD_SOURCEVERSION"))
+	// Pull request builds run on a merge ref, the source branch is what was pushed
+	setDefault(&in.Branch, strings.TrimPrefix(getenv("SYSTEM_PULLREQUEST_SOURCEBRANCH"), "refs/heads/"))
+	setDefault(&in.Branch, getenv("BUILD_SOURCEBRANCHNAME"))
+	setDefault(&in.Author, getenv("BUILD_REQUESTEDFOR"))
+	setDefault(&in.AuthorEmail, getenv("BUILD_REQUESTEDFOREMAIL"))
+	setDefault(&in.CommitMsg, getenv("BUILD_SOURCEVERSIONMESSAGE"))
+	setDefault(&in.WorkflowName, getenv("BUILD_DEFINITIONNAME"))
+	setDefault(&in.Repository, getenv("BUILD_REPOSITORY_NAME"))
+	setDefault(&in.RunID, getenv("BUILD_BUILDID"))
+	setDefault(&in.PRNumber, getenv("SYSTEM_PULLREQUEST_PULLREQUESTNUMBER"))
+	setDefault(&in.PRNumber, getenv("SYSTEM_PULLREQUEST_PULLREQUESTID"))
+	if ref := getenv("BUILD_SOURCEBRANCH"); strings.HasPrefix(ref, "refs/tags/") {
+		setDefault(&in.ReleaseTag, strings.TrimPrefix(ref, "refs/tags/"))
+	}
+
+	if collection, project := getenv("SYSTEM_COLLECTIONURI"), getenv("SYSTEM_TEAMPROJECT"); collection != "" && project != "" && in.RunID != "" {
+		setDefault(&in.RunURL, fmt.Sprintf("%s%s/_build/results?buildId=%s",
+			strings.TrimSuffix(collection, "/")+"/", url.PathEscape(project), in.RunID))
+	}
+	if uri := getenv("BUILD_REPOSITORY_URI"); uri != "" {
+		setDefault(&in.CommitURL, commitURL(stripUserInfo(repoWebURL(uri)), in.CommitSha))
+	}
+}
+
+// stripUserInfo removes the "org@" prefix Azure Repos adds to https clone URLs
+func stripUserInfo(repoURL string) string {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return repoURL
+	}
+	u.User = nil
+	return u.String()
+}
+
+// azureLoggingSink sets output variables with logging commands so downstream jobs and stages can read them
+type azureLoggingSink struct {
+	w io.Writer
+}
+
+func (s azureLoggingSink) Write(outputs map[string]string) error {
+	for _, key := range sortedKeys(outputs) {
+		fmt.Fprintf(s.w, "##vso[task.setvariable variable=%s;isOutput=true]%s\n", key, azureEscaper.Replace(outputs[key]))
+	}
+	return nil
+}
+
+// azureEscaper escapes logging command data, the agent decodes it back and a raw newline
+// or "]" would otherwise end the command early
+var azureEscaper = strings.NewReplacer("%", "%AZP25", "\r", "%0D", "\n", "%0A", "]", "%5D", ";", "%3B")
+
+func azureSink(_ ActionInputs, _ func(string) string, stdout io.Writer) outputSink {
+	return azureLoggingSink{w: stdout}
+}
+
+// azureWarning logs a warning issue with a logging command
+func azureWarning(w io.Writer, msg string) {
+	fmt.Fprintf(w, "##vso[task.logissue type=warning]%s\n", azureEscaper.Replace(msg))
+}
@@ -0,0 +1,26 @@
+package main
+
+import "fmt"
+
+// discoverBitbucketContext reads the Bitbucket Pipelines default variables
+func discoverBitbucketContext(in *ActionInputs, getenv func(string) string) {
+	setDefault(&in.CommitSha, getenv("BITBUCKET_COMMIT"))
+	setDefault(&in.Branch, getenv("BITBUCKET_BRANCH"))
+	setDefault(&in.ReleaseTag, getenv("BITBUCKET_TAG"))
+	setDefault(&in.Repository, getenv("BITBUCKET_REPO_FULL_NAME"))
+	setDefault(&in.RunID, getenv("BITBUCKET_BUILD_NUMBER"))
+	setDefault(&in.PRNumber, getenv("BITBUCKET_PR_ID"))
+	setDefault(&in.WorkflowName, "Bitbucket Pipelines")
+
+	repoURL := repoWebURL(getenv("BITBUCKET_GIT_HTTP_ORIGIN"))
+	if repoURL == "" {
+		return
+	}
+	if in.RunID != "" {
+		setDefault(&in.RunURL, fmt.Sprintf("%s/pipelines/results/%s", repoURL, in.RunID))
+	}
+	if in.PRNumber != "" {
+		setDefault(&in.PRURL, fmt.Sprintf("%s/pull-requests/%s", repoURL, in.PRNumber))
+	}
+	setDefault(&in.CommitURL, commitURL(repoURL, in.CommitSha))
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+)
+
+// buildkiteAgent runs buildkite-agent with args and returns its trimmed stdout, replaced in tests
+var buildkiteAgent = func(args ...string) (string, error) {
+	out, err := exec.Command("buildkite-agent", args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("buildkite-agent %s failed- %s", strings.Join(args, " "), err.Error())
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// discoverBuildkiteContext reads the Buildkite environment variables, the message ID of a
+// previous step is read back from the build meta-data
+func discoverBuildkiteContext(in *ActionInputs, getenv func(string) string) {
+	setDefault(&in.CommitSha, getenv("BUILDKITE_COMMIT"))
+	setDefault(&in.Branch, getenv("BUILDKITE_BRANCH"))
+	setDefault(&in.Author, getenv("BUILDKITE_BUILD_AUTHOR"))
+	setDefault(&in.AuthorEmail, getenv("BUILDKITE_BUILD_AUTHOR_EMAIL"))
+	setDefault(&in.Pusher, getenv("BUILDKITE_BUILD_CREATOR"))
+	setDefault(&in.CommitMsg, getenv("BUILDKITE_MESSAGE"))
+	setDefault(&in.WorkflowName, getenv("BUILDKITE_PIPELINE_NAME"))
+	setDefault(&in.RunID, getenv("BUILDKITE_BUILD_NUMBER"))
+	setDefault(&in.RunURL, getenv("BUILDKITE_BUILD_URL"))
+	setDefault(&in.ReleaseTag, getenv("BUILDKITE_TAG"))
+	if pr := getenv("BUILDKITE_PULL_REQUEST"); pr != "" && pr != "false" {
+		setDefault(&in.PRNumber, pr)
+	}
+	if remote := getenv("BUILDKITE_REPO"); remote != "" {
+		setDefault(&in.CommitURL, commitURL(repoWebURL(remote), in.CommitSha))
+	}
+	if in.MsgID == "" {
+		msgId, err := buildkiteAgent("meta-data", "get", "message_id", "--default", "")
+		if err != nil {
+			slog.Warn("Failed to read message_id meta-data", slog.String("error", err.Error()))
+			return
+		}
+		in.MsgID = msgId
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// ciEnvironment describes a CI system: how to recognise it, how to fill commit/build
+// context from its variables and where step outputs are persisted. discover only fills
+// empty fields (setDefault), explicit inputs always win over discovered values.
+type ciEnvironment struct {
+	name     string
+	title    string // named in the message header
+	detect   func(getenv func(string) string) bool
+	discover func(in *ActionInputs, getenv func(string) string)
+	sink     func(in ActionInputs, getenv func(string) string, stdout io.Writer) outputSink
+	// warn surfaces a non-fatal failure in the CI UI, nil logs a warning
+	warn func(w io.Writer, msg string)
+}
+
+// ciEnvironments is checked in order, the first match wins
+var ciEnvironments = []ciEnvironment{
+	{
+		name:     "github",
+		title:    "Github Workflow",
+		detect:   envEquals("GITHUB_ACTIONS", "true"),
+		discover: discoverGithubContext,
+		sink:     githubSink,
+		warn:     githubWarning,
+	},
+	{
+		name:     "gitlab",
+		title:    "GitLab Pipeline",
+		detect:   envEquals("GITLAB_CI", "true"),
+		discover: discoverGitlabContext,
+		sink:     dotenvReportSink,
+	},
+	{
+		name:     "woodpecker",
+		title:    "Woodpecker Pipeline",
+		detect:   envEquals("CI", "woodpecker"),
+		discover: discoverWoodpeckerContext,
+		sink:     woodpeckerSink,
+	},
+	{
+		name:     "drone",
+		title:    "Drone Build",
+		detect:   envEquals("DRONE", "true"),
+		discover: discoverDroneContext,
+		sink:     droneSink,
+	},
+	{
+		name:     "azure",
+		title:    "Azure Pipeline",
+		detect:   envEquals("TF_BUILD", "true"),
+		discover: discoverAzureContext,
+		sink:     azureSink,
+		warn:     azureWarning,
+	},
+	{
+		name:     "buildkite",
+		title:    "Buildkite Build",
+		detect:   envEquals("BUILDKITE", "true"),
+		discover: discoverBuildkiteContext,
+		sink:     buildkiteSink,
+	},
+	{
+		name:     "circleci",
+		title:    "CircleCI Pipeline",
+		detect:   envEquals("CIRCLECI", "true"),
+		discover: discoverCircleCIContext,
+		sink:     consoleSink,
+	},
+	{
+		name:     "bitbucket",
+		title:    "Bitbucket Pipeline",
+		detect:   envSet("BITBUCKET_BUILD_NUMBER"),
+		discover: discoverBitbucketContext,
+		sink:     consoleSink,
+	},
+	{
+		name:     "jenkins",
+		title:    "Jenkins Build",
+		detect:   envSet("JENKINS_URL"),
+		discover: discoverJenkinsContext,
+		sink:     consoleSink,
+	},
+}
+
+// localEnvironment is used when no CI system is detected
+var localEnvironment = ciEnvironment{
+	name:     "local",
+	title:    "CI/CD Pipeline",
+	detect:   func(func(string) string) bool { return true },
+	discover: func(*ActionInputs, func(string) string) {},
+	sink:     consoleSink,
+}
+
+// detectCIEnvironment returns the CI system the notifier is running in
+func detectCIEnvironment(getenv func(string) string) ciEnvironment {
+	for _, env := range ciEnvironments {
+		if env.detect(getenv) {
+			return env
+		}
+	}
+	return localEnvironment
+}
+
+// warning reports msg as an annotation of the CI system, falling back to the log
+func (env ciEnvironment) warning(w io.Writer, msg string) {
+	if env.warn == nil {
+		slog.Warn(msg)
+		return
+	}
+	env.warn(w, msg)
+}
+
+func envEquals(key, value string) func(func(string) string) bool {
+	return func(getenv func(string) string) bool {
+		return strings.EqualFold(getenv(key), value)
+	}
+}
+
+func envSet(key string) func(func(string) string) bool {
+	return func(getenv func(string) string) bool {
+		return getenv(key) != ""
+	}
+}
+
+// setDefault assigns value to field only when field is empty
+func setDefault(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
+
+// splitAuthor splits a "Name <email>" author string
+func splitAuthor(author string) (string, string) {
+	name, rest, found := strings.Cut(author, "<")
+	if !found {
+		return strings.TrimSpace(author), ""
+	}
+	return strings.TrimSpace(name), strings.TrimSpace(strings.TrimSuffix(rest, ">"))
+}
+
+// repoWebURL converts a git remote (git@host:org/repo.git or https://host/org/repo.git) to its web URL
+func repoWebURL(remote string) string {
+	remote = strings.TrimSuffix(strings.TrimSpace(remote), ".git")
+	if rest, ok := strings.CutPrefix(remote, "git@"); ok {
+		host, path, _ := strings.Cut(rest, ":")
+		return fmt.Sprintf("https://%s/%s", host, path)
+	}
+	if rest, ok := strings.CutPrefix(remote, "ssh://git@"); ok {
+		return "https://" + rest
+	}
+	return strings.Replace(remote, "http://", "https://", 1)
+}
+
+// commitURL returns the commit page for the hosting service behind repoURL
+func commitURL(repoURL, sha string) string {
+	if repoURL == "" || sha == "" {
+		return ""
+	}
+	switch {
+	case strings.Contains(repoURL, "bitbucket"):
+		return fmt.Sprintf("%s/commits/%s", repoURL, sha)
+	case strings.Contains(repoURL, "gitlab"):
+		return fmt.Sprintf("%s/-/commit/%s", repoURL, sha)
+	default:
+		return fmt.Sprintf("%s/commit/%s", repoURL, sha)
+	}
+}
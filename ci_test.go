@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestDetectCIEnvironment(t *testing.T) {
+	tests := []struct {
+		env      map[string]string
+		expected string
+	}{
+		{map[string]string{"GITHUB_ACTIONS": "true"}, "github"},
+		{map[string]string{"GITLAB_CI": "true"}, "gitlab"},
+		{map[string]string{"CI": "woodpecker", "CI_COMMIT_SHA": "abc123"}, "woodpecker"},
+		{map[string]string{"DRONE": "true", "CI": "true"}, "drone"},
+		{map[string]string{"TF_BUILD": "True"}, "azure"},
+		{map[string]string{"BUILDKITE": "true"}, "buildkite"},
+		{map[string]string{"CIRCLECI": "true"}, "circleci"},
+		{map[string]string{"BITBUCKET_BUILD_NUMBER": "7"}, "bitbucket"},
+		{map[string]string{"JENKINS_URL": "https://ci.example.com/", "BUILD_URL": "https://ci.example.com/job/x/1/"}, "jenkins"},
+		{map[string]string{"GITHUB_SHA": "abc123"}, "local"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			env := detectCIEnvironment(func(key string) string { return tt.env[key] })
+			if env.name != tt.expected {
+				t.Errorf("detectCIEnvironment() = %s, expected %s", env.name, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDiscoverCIContext(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected ActionInputs
+	}{
+		{
+			name: "jenkins",
+			env: map[string]string{
+				"JENKINS_URL":  "https://ci.example.com/",
+				"GIT_COMMIT":   "abc123",
+				"GIT_BRANCH":   "origin/main",
+				"GIT_URL":      "git@github.com:org/repo.git",
+				"JOB_NAME":     "deploy",
+				"BUILD_NUMBER": "12",
+				"BUILD_URL":    "https://ci.example.com/job/deploy/12/",
+			},
+			expected: ActionInputs{
+				CommitSha:    "abc123",
+				Branch:       "main",
+				WorkflowName: "deploy",
+				RunID:        "12",
+				RunURL:       "https://ci.example.com/job/deploy/12/",
+				CommitURL:    "https://github.com/org/repo/commit/abc123",
+			},
+		},
+		{
+			name: "circleci",
+			env: map[string]string{
+				"CIRCLECI":                "true",
+				"CIRCLE_SHA1":             "abc123",
+				"CIRCLE_BRANCH":           "feature",
+				"CIRCLE_USERNAME":         "dev",
+				"CIRCLE_JOB":              "build",
+				"CIRCLE_BUILD_NUM":        "5",
+				"CIRCLE_BUILD_URL":        "https://circleci.com/gh/org/repo/5",
+				"CIRCLE_PULL_REQUEST":     "https://github.com/org/repo/pull/3",
+				"CIRCLE_PROJECT_USERNAME": "org",
+				"CIRCLE_PROJECT_REPONAME": "repo",
+				"CIRCLE_REPOSITORY_URL":   "https://github.com/org/repo.git",
+			},
+			expected: ActionInputs{
+				CommitSha:    "abc123",
+				Branch:       "feature",
+				Pusher:       "dev",
+				WorkflowName: "build",
+				Repository:   "org/repo",
+				RunID:        "5",
+				RunURL:       "https://circleci.com/gh/org/repo/5",
+				CommitURL:    "https://github.com/org/repo/commit/abc123",
+				PRNumber:     "3",
+				PRURL:        "https://github.com/org/repo/pull/3",
+			},
+		},
+		{
+			name: "bitbucket",
+			env: map[string]string{
+				"BITBUCKET_BUILD_NUMBER":    "8",
+				"BITBUCKET_COMMIT":          "abc123",
+				"BITBUCKET_BRANCH":          "main",
+				"BITBUCKET_REPO_FULL_NAME":  "org/repo",
+				"BITBUCKET_GIT_HTTP_ORIGIN": "http://bitbucket.org/org/repo",
+			},
+			expected: ActionInputs{
+				CommitSha:    "abc123",
+				Branch:       "main",
+				WorkflowName: "Bitbucket Pipelines",
+				Repository:   "org/repo",
+				RunID:        "8",
+				RunURL:       "https://bitbucket.org/org/repo/pipelines/results/8",
+				CommitURL:    "https://bitbucket.org/org/repo/commits/abc123",
+			},
+		},
+		{
+			name: "azure",
+			env: map[string]string{
+				"TF_BUILD":                   "True",
+				"BUILD_SOURCEVERSION":        "abc123",
+				"BUILD_SOURCEBRANCHNAME":     "main",
+				"BUILD_REQUESTEDFOR":         "Jane Doe",
+				"BUILD_DEFINITIONNAME":       "deploy",
+				"BUILD_BUILDID":              "77",
+				"BUILD_REPOSITORY_NAME":      "repo",
+				"BUILD_REPOSITORY_URI":       "https://org@dev.azure.com/org/My Project/_git/repo",
+				"SYSTEM_COLLECTIONURI":       "https://dev.azure.com/org/",
+				"SYSTEM_TEAMPROJECT":         "My Project",
+				"BUILD_SOURCEVERSIONMESSAGE": "fix bug",
+			},
+			expected: ActionInputs{
+				CommitSha:    "abc123",
+				Branch:       "main",
+				Author:       "Jane Doe",
+				CommitMsg:    "fix bug",
+				WorkflowName: "deploy",
+				Repository:   "repo",
+				RunID:        "77",
+				RunURL:       "https://dev.azure.com/org/My%20Project/_build/results?buildId=77",
+				CommitURL:    "https://dev.azure.com/org/My%20Project/_git/repo/commit/abc123",
+			},
+		},
+		{
+			name: "buildkite",
+			env: map[string]string{
+				"BUILDKITE":                    "true",
+				"BUILDKITE_COMMIT":             "abc123",
+				"BUILDKITE_BRANCH":             "main",
+				"BUILDKITE_BUILD_AUTHOR":       "Jane",
+				"BUILDKITE_BUILD_AUTHOR_EMAIL": "jane@example.com",
+				"BUILDKITE_MESSAGE":            "fix bug",
+				"BUILDKITE_PIPELINE_NAME":      "deploy",
+				"BUILDKITE_BUILD_NUMBER":       "4",
+				"BUILDKITE_BUILD_URL":          "https://buildkite.com/org/deploy/builds/4",
+				"BUILDKITE_PULL_REQUEST":       "false",
+				"BUILDKITE_REPO":               "git@github.com:org/repo.git",
+			},
+			expected: ActionInputs{
+				MsgID:        "1700000000.000100",
+				CommitSha:    "abc123",
+				Branch:       "main",
+				Author:       "Jane",
+				AuthorEmail:  "jane@example.com",
+				CommitMsg:    "fix bug",
+				WorkflowName: "deploy",
+				RunID:        "4",
+				RunURL:       "https://buildkite.com/org/deploy/builds/4",
+				CommitURL:    "https://github.com/org/repo/commit/abc123",
+			},
+		},
+	}
+
+	metadata := map[string]string{"message_id": "1700000000.000100"}
+	defer stubBuildkiteAgent(metadata)()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(key string) string { return tt.env[key] }
+			env := detectCIEnvironment(getenv)
+			if env.name != tt.name {
+				t.Fatalf("detectCIEnvironment() = %s, expected %s", env.name, tt.name)
+			}
+			in := ActionInputs{}
+			env.discover(&in, getenv)
+			if in != tt.expected {
+				t.Errorf("discover() = %+v, expected %+v", in, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuildkiteMetadataSink(t *testing.T) {
+	metadata := map[string]string{}
+	defer stubBuildkiteAgent(metadata)()
+
+	sink := buildkiteSink(ActionInputs{}, func(string) string { return "" }, io.Discard)
+	if err := sink.Write(map[string]string{"message_id": "123", "channel_id": "C1"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if metadata["message_id"] != "123" || metadata["channel_id"] != "C1" {
+		t.Errorf("meta-data = %v", metadata)
+	}
+}
+
+// stubBuildkiteAgent replaces buildkite-agent meta-data get/set with an in-memory map
+func stubBuildkiteAgent(metadata map[string]string) func() {
+	original := buildkiteAgent
+	buildkiteAgent = func(args ...string) (string, error) {
+		switch args[1] {
+		case "set":
+			metadata[args[2]] = args[3]
+		case "get":
+			return metadata[args[2]], nil
+		}
+		return "", nil
+	}
+	return func() { buildkiteAgent = original }
+}
+
+func TestAzureLoggingSink(t *testing.T) {
+	var out bytes.Buffer
+	outputs := map[string]string{"message_id": "1700000000.000100", "reply": "50% done; see [log]\r\nnext"}
+	if err := (azureLoggingSink{w: &out}).Write(outputs); err != nil {
+		t.Fatal(err)
+	}
+	expected := "##vso[task.setvariable variable=message_id;isOutput=true]1700000000.000100\n" +
+		"##vso[task.setvariable variable=reply;isOutput=true]50%AZP25 done%3B see [log%5D%0D%0Anext\n"
+	if got := out.String(); got != expected {
+		t.Errorf("logging commands = %q, expected %q", got, expected)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import "strings"
+
+// discoverCircleCIContext reads the CircleCI built-in variables
+func discoverCircleCIContext(in *ActionInputs, getenv func(string) string) {
+	setDefault(&in.CommitSha, getenv("CIRCLE_SHA1"))
+	setDefault(&in.Branch, getenv("CIRCLE_BRANCH"))
+	setDefault(&in.Pusher, getenv("CIRCLE_USERNAME"))
+	setDefault(&in.WorkflowName, getenv("CIRCLE_JOB"))
+	setDefault(&in.RunID, getenv("CIRCLE_BUILD_NUM"))
+	setDefault(&in.RunURL, getenv("CIRCLE_BUILD_URL"))
+	setDefault(&in.ReleaseTag, getenv("CIRCLE_TAG"))
+	setDefault(&in.PRNumber, getenv("CIRCLE_PR_NUMBER"))
+	setDefault(&in.PRURL, getenv("CIRCLE_PULL_REQUEST"))
+	if in.PRNumber == "" && in.PRURL != "" {
+		in.PRNumber = in.PRURL[strings.LastIndex(in.PRURL, "/")+1:]
+	}
+	if owner, repo := getenv("CIRCLE_PROJECT_USERNAME"), getenv("CIRCLE_PROJECT_REPONAME"); owner != "" && repo != "" {
+		setDefault(&in.Repository, owner+"/"+repo)
+	}
+	if remote := getenv("CIRCLE_REPOSITORY_URL"); remote != "" {
+		setDefault(&in.CommitURL, commitURL(repoWebURL(remote), in.CommitSha))
+	}
+}
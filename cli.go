@@ -0,0 +1,154 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Exit codes returned by the CLI
+const (
+	exitOK      = 0
+	exitFailure = 1   // the notification could not be sent/updated
+	exitUsage   = 2   // bad command or flags
+	exitTimeout = 124 // the timeout input expired, same as timeout(1)
+	exitSignal  = 130 // cancelled by SIGINT/SIGTERM (job cancellation)
+)
+
+// commands lists the CLI subcommands, each maps to an action
+var commands = []struct {
+	name  string
+	usage string
+}{
+	{"send", "Send a new message"},
+	{"aggregate", "Report a matrix leg (--step_name, --status) into one grid message per --key"},
+	{"upsert", "Update the message sent with --key, or send it if there is none"},
+	{"update", "Append to an existing message (requires --msg_id)"},
+	{"reply", "Reply in the thread of a message (requires --msg_id)"},
+	{"react", "Add/remove emoji reactions on a message (requires --msg_id)"},
+	{"pin", "Pin a message, unpinning the previous deploy (requires --msg_id)"},
+	{"unpin", "Unpin a message (requires --msg_id)"},
+	{"set_topic", "Set the channel topic (Telegram: description) to --message or the deployed version"},
+	{"delete", "Delete a message (requires --msg_id)"},
+	{"cleanup", "Delete the bot's messages older than --older_than"},
+	{"verify", "Check the API key and, with --channel_id, channel access and scopes"},
+	{"pre", "Docker pre-entrypoint: send the started message when auto_finalize is set"},
+	{"post", "Docker post-entrypoint: update the started message with the job status"},
+	{"preview", "Print the payload send would post, without calling the API"},
+}
+
+// cliInputs lists every supported input, each one is registered as a --flag
+// and falls back to INPUT_<NAME>, PLUGIN_<NAME> or NOTIFIER_<NAME>
+var cliInputs = []struct {
+	name  string
+	usage string
+}{
+	{"channel", "Notification channel: slack or telegram"},
+	{"message", "Message to send"},
+	{"api_key", "API key for the selected channel"},
+	{"channel_id", "Channel/chat ID for the selected platform"},
+	{"msg_id", "Message ID for update/delete"},
+	{"key", "Correlation key (e.g. repo+run_id) upsert finds the message by"},
+	{"update_mode", "How update changes the message: edit (re-render) or thread (reply)"},
+	{"reply_broadcast", "Also show Slack thread replies in the channel (true/false)"},
+	{"topic_id", "Telegram forum topic to post in (message_thread_id)"},
+	{"reactions", "Comma separated emoji react adds (e.g. white_check_mark)"},
+	{"remove_reactions", "Comma separated emoji react removes (e.g. hourglass)"},
+	{"unpin_previous", "pin also unpins the bot's previously pinned message (true/false)"},
+	{"step_name", "Named step to start or complete, updated in place"},
+	{"status", "Step status: pending, running, success, failure, cancelled or skipped"},
+	{"duration", "Duration of the matrix leg (e.g. 3m12s)"},
+	{"step_url", "Link to the job of the matrix leg, defaults to the run"},
+	{"matrix_total", "Number of matrix legs, the matrix is running until all of them reported"},
+	{"add_commit_info", "Add commit information to the message (true/false)"},
+	{"image_tag", "Docker image tag"},
+	{"commit_sha", "Commit SHA"},
+	{"branch", "Branch name"},
+	{"author", "Commit author"},
+	{"commit_time", "Commit time"},
+	{"commit_msg", "Commit message"},
+	{"workflow_name", "Workflow name"},
+	{"timezone", "Timezone used for the message"},
+	{"slack_blocks", "Render Slack messages with Block Kit (true/false)"},
+	{"run_url", "Link to the workflow run"},
+	{"commit_url", "Link to the commit"},
+	{"compare_url", "Link to the compare view"},
+	{"dotenv_file", "dotenv report/state file for outputs"},
+	{"state_file", "JSON file keeping message timelines, needed to update Telegram messages"},
+	{"retries", "Total attempts per API call, 1 disables retries"},
+	{"retry_delay", "First retry delay (e.g. 1s), doubled on every retry"},
+	{"auto_finalize", "Send a started message in pre and update it with the job status in post (true/false)"},
+	{"github_token", "Token used to read the job status in post"},
+	{"dry_run", "Print the payloads instead of calling the API (true/false)"},
+	{"fail_on_error", "Exit non-zero when the notification fails (true/false), config errors always fail"},
+	{"older_than", "Minimum age of the messages cleanup deletes (e.g. 24h)"},
+	{"timeout", "Overall timeout for the API calls (e.g. 2m), 0 disables it"},
+	{"action", "Action to perform, same as the command"},
+}
+
+// parseArgs parses "[command] [--flags]", only flags that were set are returned
+// The command is empty when running as a GitHub Action without args
+func parseArgs(args []string, output io.Writer) (string, map[string]string, error) {
+	var command string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command = strings.ToLower(args[0])
+		args = args[1:]
+		if command == "help" {
+			printUsage(output, nil)
+			return "", nil, flag.ErrHelp
+		}
+		if !isCommand(command) {
+			return "", nil, fmt.Errorf("unknown command %q", command)
+		}
+	}
+
+	fs := flag.NewFlagSet("cicd-notifier", flag.ContinueOnError)
+	fs.SetOutput(output)
+	values := make(map[string]*string, len(cliInputs))
+	for _, input := range cliInputs {
+		values[input.name] = fs.String(input.name, "", input.usage)
+	}
+	fs.Usage = func() { printUsage(output, fs) }
+	if err := fs.Parse(args); err != nil {
+		return "", nil, err
+	}
+	if fs.NArg() > 0 {
+		return "", nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	flags := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		flags[f.Name] = *values[f.Name]
+	})
+	if command != "" {
+		flags["action"] = command
+	}
+	return command, flags, nil
+}
+
+func isCommand(name string) bool {
+	for _, c := range commands {
+		if c.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func printUsage(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintln(w, "Usage: cicd-notifier <command> [flags]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.usage)
+	}
+	fmt.Fprintln(w, "\nFlags fall back to INPUT_<NAME>, PLUGIN_<NAME> or NOTIFIER_<NAME> environment variables.")
+	fmt.Fprintln(w, "Without a command the action is read from INPUT_ACTION (GitHub Action mode).")
+	if fs == nil {
+		return
+	}
+	fmt.Fprintln(w, "\nFlags:")
+	fs.PrintDefaults()
+	fmt.Fprintf(w, "\nExit codes: %d success, %d failure, %d usage error, %d timeout, %d cancelled by SIGINT/SIGTERM\n",
+		exitOK, exitFailure, exitUsage, exitTimeout, exitSignal)
+}
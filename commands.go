@@ -0,0 +1,165 @@
+package main
+
+import (
+	"cicd-notifier/pkg/notifier"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+)
+
+// message builds the notification for cfg, commit info is included when add_commit_info is set
+func message(cfg ActionInputs) notifier.Message {
+	// validateInputs already rejected unknown statuses
+	status, _ := notifier.ParseStatus(cfg.Status)
+	msg := notifier.Message{Text: cfg.Message, Step: cfg.StepName, Status: status, Key: cfg.Key}
+	if cfg.Action == "aggregate" {
+		msg.Matrix, msg.Duration, msg.URL, msg.MatrixTotal = true, cfg.Duration, cfg.StepURL, cfg.MatrixTotal
+	}
+	if cfg.AddCommitInfo {
+		msg.Commit = commitInfo(cfg)
+	}
+	return msg
+}
+
+func sendMessage(ctx context.Context, n *notifier.Notifier, cfg ActionInputs, outputs map[string]string) error {
+	ref, err := n.Send(ctx, message(cfg))
+	if err != nil {
+		return fmt.Errorf("failed to post %s message- %w", cfg.Channel, err)
+	}
+	addIDs(outputs, ref)
+	return nil
+}
+
+func updateMessage(ctx context.Context, n *notifier.Notifier, cfg ActionInputs, outputs map[string]string) error {
+	if cfg.UpdateMode == "thread" {
+		return replyMessage(ctx, n, cfg, outputs)
+	}
+	msg := message(cfg)
+	msg.Commit = nil
+	ref, err := n.Update(ctx, cfg.MsgID, msg)
+	if err != nil {
+		return fmt.Errorf("failed to update %s message- %w", cfg.Channel, err)
+	}
+	addIDs(outputs, ref)
+	return nil
+}
+
+// upsertMessage updates the message sent with key, or sends it when there is none yet.
+// Jobs that found no message at the same time each send one, so after sending the key is looked up
+// again: every job converges on the oldest message and merges into it, a newer duplicate is deleted.
+func upsertMessage(ctx context.Context, n *notifier.Notifier, cfg ActionInputs, outputs map[string]string) error {
+	ref, found, err := n.Find(ctx, cfg.Key)
+	if err != nil {
+		return fmt.Errorf("failed to find %s message %q- %w", cfg.Channel, cfg.Key, err)
+	}
+	if !found {
+		sent, err := n.Send(ctx, message(cfg))
+		if err != nil {
+			return fmt.Errorf("failed to post %s message- %w", cfg.Channel, err)
+		}
+		ref, found, err = n.Find(ctx, cfg.Key)
+		if err != nil {
+			return fmt.Errorf("failed to find %s message %q- %w", cfg.Channel, cfg.Key, err)
+		}
+		if !found || ref.MessageID == sent.MessageID {
+			outputs["created"] = "true"
+			addIDs(outputs, sent)
+			return nil
+		}
+		slog.Info("Another job sent the message at the same time, merging into the oldest one", slog.String("key", cfg.Key))
+		if err := n.Delete(ctx, sent.MessageID); err != nil {
+			return fmt.Errorf("failed to delete duplicate %s message- %w", cfg.Channel, err)
+		}
+	}
+	outputs["created"] = "false"
+	cfg.MsgID = ref.MessageID
+	return updateMessage(ctx, n, cfg, outputs)
+}
+
+// replyMessage posts in the thread of msg_id, message_id stays the parent so later steps keep replying to it
+func replyMessage(ctx context.Context, n *notifier.Notifier, cfg ActionInputs, outputs map[string]string) error {
+	msg := message(cfg)
+	msg.Commit = nil
+	ref, err := n.Reply(ctx, cfg.MsgID, msg)
+	if err != nil {
+		return fmt.Errorf("failed to reply to %s message- %w", cfg.Channel, err)
+	}
+	addIDs(outputs, notifier.Ref{ChannelID: ref.ChannelID, MessageID: cfg.MsgID})
+	outputs["reply_id"] = ref.MessageID
+	return nil
+}
+
+// reactToMessage swaps the reactions on msg_id, the message itself is left untouched
+func reactToMessage(ctx context.Context, n *notifier.Notifier, cfg ActionInputs, outputs map[string]string) error {
+	if err := n.React(ctx, cfg.MsgID, splitList(cfg.ReactAdd), splitList(cfg.ReactRemove)); err != nil {
+		return fmt.Errorf("failed to react to %s message- %w", cfg.Channel, err)
+	}
+	addIDs(outputs, notifier.Ref{MessageID: cfg.MsgID})
+	return nil
+}
+
+// pinMessage pins msg_id, by default the bot's previously pinned deploy is unpinned
+func pinMessage(ctx context.Context, n *notifier.Notifier, cfg ActionInputs, outputs map[string]string) error {
+	if err := n.Pin(ctx, cfg.MsgID, cfg.UnpinPrevious); err != nil {
+		return fmt.Errorf("failed to pin %s message- %w", cfg.Channel, err)
+	}
+	addIDs(outputs, notifier.Ref{MessageID: cfg.MsgID})
+	return nil
+}
+
+func unpinMessage(ctx context.Context, n *notifier.Notifier, cfg ActionInputs) error {
+	if err := n.Unpin(ctx, cfg.MsgID); err != nil {
+		return fmt.Errorf("failed to unpin %s message- %w", cfg.Channel, err)
+	}
+	return nil
+}
+
+// setTopic shows what is live in the channel topic
+func setTopic(ctx context.Context, n *notifier.Notifier, cfg ActionInputs) error {
+	if err := n.SetTopic(ctx, topic(cfg)); err != nil {
+		return fmt.Errorf("failed to set %s topic- %w", cfg.Channel, err)
+	}
+	return nil
+}
+
+func deleteMessage(ctx context.Context, n *notifier.Notifier, cfg ActionInputs) error {
+	if err := n.Delete(ctx, cfg.MsgID); err != nil {
+		return fmt.Errorf("failed to delete %s message- %w", cfg.Channel, err)
+	}
+	return nil
+}
+
+// cleanupMessages deletes the bot's messages older than older_than and outputs how many were deleted
+func cleanupMessages(ctx context.Context, n *notifier.Notifier, cfg ActionInputs, outputs map[string]string) error {
+	deleted, err := n.Cleanup(ctx, cfg.OlderThan)
+	outputs["deleted"] = strconv.Itoa(deleted)
+	if err != nil {
+		return fmt.Errorf("failed to clean up %s messages- %w", cfg.Channel, err)
+	}
+	return nil
+}
+
+func verifyCredentials(ctx context.Context, n *notifier.Notifier, stdout io.Writer) error {
+	identity, err := n.Verify(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, identity)
+	return nil
+}
+
+// previewMessage prints the payload send would post without calling any API
+func previewMessage(cfg ActionInputs, msg notifier.Message, stdout io.Writer) error {
+	_, err := newDryRunProvider(cfg, stdout).Send(context.Background(), cfg.ChannelId, msg)
+	return err
+}
+
+// addIDs records the message and channel IDs as step outputs
+func addIDs(outputs map[string]string, ref notifier.Ref) {
+	outputs["message_id"] = ref.MessageID
+	if ref.ChannelID != "" {
+		outputs["channel_id"] = ref.ChannelID
+	}
+}
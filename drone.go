@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// pluginStateFile is where plugin mode persists outputs inside the shared workspace
+const pluginStateFile = ".cicd-notifier.env"
+
+// discoverDroneContext reads the Drone DRONE_* variables
+func discoverDroneContext(in *ActionInputs, getenv func(string) string) {
+	setDefault(&in.CommitSha, getenv("DRONE_COMMIT_SHA"))
+	setDefault(&in.Branch, getenv("DRONE_SOURCE_BRANCH"))
+	setDefault(&in.Branch, getenv("DRONE_BRANCH"))
+	setDefault(&in.Author, getenv("DRONE_COMMIT_AUTHOR_NAME"))
+	setDefault(&in.Author, getenv("DRONE_COMMIT_AUTHOR"))
+	setDefault(&in.AuthorEmail, getenv("DRONE_COMMIT_AUTHOR_EMAIL"))
+	setDefault(&in.CommitMsg, getenv("DRONE_COMMIT_MESSAGE"))
+	setDefault(&in.WorkflowName, getenv("DRONE_STAGE_NAME"))
+	setDefault(&in.Repository, getenv("DRONE_REPO"))
+	setDefault(&in.RunID, getenv("DRONE_BUILD_NUMBER"))
+	setDefault(&in.RunURL, getenv("DRONE_BUILD_LINK"))
+	setDefault(&in.ReleaseTag, getenv("DRONE_TAG"))
+	setDefault(&in.PRNumber, getenv("DRONE_PULL_REQUEST"))
+	setDefault(&in.PRTitle, getenv("DRONE_PULL_REQUEST_TITLE"))
+	// DRONE_COMMIT_LINK is a compare link for pushes and a commit link otherwise
+	if link := getenv("DRONE_COMMIT_LINK"); strings.Contains(link, "/compare/") {
+		setDefault(&in.CompareURL, link)
+	}
+	setDefault(&in.CommitURL, commitURL(getenv("DRONE_REPO_LINK"), in.CommitSha))
+	readPluginState(in, getenv("DRONE_WORKSPACE"))
+}
+
+// discoverWoodpeckerContext reads the Woodpecker CI_* variables
+func discoverWoodpeckerContext(in *ActionInputs, getenv func(string) string) {
+	setDefault(&in.CommitSha, getenv("CI_COMMIT_SHA"))
+	setDefault(&in.Branch, getenv("CI_COMMIT_SOURCE_BRANCH"))
+	setDefault(&in.Branch, getenv("CI_COMMIT_BRANCH"))
+	setDefault(&in.Author, getenv("CI_COMMIT_AUTHOR"))
+	setDefault(&in.AuthorEmail, getenv("CI_COMMIT_AUTHOR_EMAIL"))
+	setDefault(&in.CommitMsg, getenv("CI_COMMIT_MESSAGE"))
+	setDefault(&in.WorkflowName, getenv("CI_WORKFLOW_NAME"))
+	setDefault(&in.Repository, getenv("CI_REPO"))
+	setDefault(&in.RunID, getenv("CI_PIPELINE_NUMBER"))
+	setDefault(&in.RunURL, getenv("CI_PIPELINE_URL"))
+	setDefault(&in.ReleaseTag, getenv("CI_COMMIT_TAG"))
+	setDefault(&in.PRNumber, getenv("CI_COMMIT_PULL_REQUEST"))
+	setDefault(&in.CommitURL, commitURL(getenv("CI_REPO_URL"), in.CommitSha))
+	readPluginState(in, getenv("CI_WORKSPACE"))
+}
+
+// pluginStatePath resolves the state file inside the pipeline workspace
+func pluginStatePath(in ActionInputs, workspace string) string {
+	path := in.DotenvFile
+	if path == "" {
+		path = pluginStateFile
+	}
+	if workspace != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(workspace, path)
+	}
+	return path
+}
+
+// readPluginState picks up the message written by a previous step in the same pipeline
+func readPluginState(in *ActionInputs, workspace string) {
+	if in.MsgID != "" {
+		return
+	}
+	path := pluginStatePath(*in, workspace)
+	state, err := godotenv.Read(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("Failed to read plugin state", slog.String("path", path), slog.String("error", err.Error()))
+		}
+		return
+	}
+	setDefault(&in.MsgID, state["MESSAGE_ID"])
+}
+
+// droneSink persists outputs to the workspace state file shared between pipeline steps
+func droneSink(in ActionInputs, getenv func(string) string, _ io.Writer) outputSink {
+	return fileSink{path: pluginStatePath(in, getenv("DRONE_WORKSPACE")), upperKeys: true}
+}
+
+func woodpeckerSink(in ActionInputs, getenv func(string) string, _ io.Writer) outputSink {
+	return fileSink{path: pluginStatePath(in, getenv("CI_WORKSPACE")), upperKeys: true}
+}
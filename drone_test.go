@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDronePluginState(t *testing.T) {
+	workspace := t.TempDir()
+	env := map[string]string{
+		"DRONE":            "true",
+		"DRONE_WORKSPACE":  workspace,
+		"DRONE_COMMIT_SHA": "abc123",
+		"DRONE_BRANCH":     "main",
+		"DRONE_REPO_LINK":  "https://github.com/org/repo",
+		"DRONE_BUILD_LINK": "https://drone.example.com/org/repo/3",
+	}
+	getenv := func(key string) string { return env[key] }
+
+	// First step sends and persists the message ID into the workspace
+	sink := droneSink(ActionInputs{}, getenv, io.Discard)
+	if err := sink.Write(map[string]string{"message_id": "1700000000.000100"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, pluginStateFile)); err != nil {
+		t.Fatalf("state file not written: %v", err)
+	}
+
+	// A later step discovers it without msg_id being passed
+	in := ActionInputs{}
+	discoverDroneContext(&in, getenv)
+	expected := ActionInputs{
+		MsgID:     "1700000000.000100",
+		CommitSha: "abc123",
+		Branch:    "main",
+		RunURL:    "https://drone.example.com/org/repo/3",
+		CommitURL: "https://github.com/org/repo/commit/abc123",
+	}
+	if in != expected {
+		t.Errorf("discoverDroneContext() = %+v, expected %+v", in, expected)
+	}
+}
+
+func TestReadInputsPluginPrefix(t *testing.T) {
+	os.Setenv("PLUGIN_CHANNEL", "telegram")
+	os.Setenv("PLUGIN_API_KEY", "plugin_key")
+	os.Setenv("INPUT_API_KEY", "input_key")
+	defer func() {
+		os.Unsetenv("PLUGIN_CHANNEL")
+		os.Unsetenv("PLUGIN_API_KEY")
+		os.Unsetenv("INPUT_API_KEY")
+	}()
+
+	inputs := readInputs(os.Environ())
+
+	if inputs["channel"] != "telegram" {
+		t.Errorf("readInputs() channel = %s, expected telegram", inputs["channel"])
+	}
+	if inputs["api_key"] != "input_key" {
+		t.Errorf("readInputs() api_key = %s, expected INPUT_API_KEY to win", inputs["api_key"])
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"cicd-notifier/pkg/notifier"
+	"cicd-notifier/pkg/slack"
+	"cicd-notifier/pkg/telegram"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// dryRunProvider prints the request each call would make instead of calling the API
+type dryRunProvider struct {
+	channel   string
+	blocks    bool
+	broadcast bool
+	topic     string
+	w         io.Writer
+}
+
+func newDryRunProvider(cfg ActionInputs, w io.Writer) *dryRunProvider {
+	return &dryRunProvider{
+		channel:   strings.ToLower(cfg.Channel),
+		blocks:    cfg.SlackBlocks,
+		broadcast: cfg.ReplyBroadcast,
+		topic:     cfg.TopicID,
+		w:         w,
+	}
+}
+
+func (p *dryRunProvider) Send(_ context.Context, channelID string, msg notifier.Message) (notifier.Ref, error) {
+	if p.channel == "slack" {
+		payload, err := slack.RenderMessage(channelID, msg, p.blocks)
+		if err != nil {
+			return notifier.Ref{}, err
+		}
+		return notifier.Ref{ChannelID: channelID}, p.print("chat.postMessage", payload)
+	}
+	payload := telegram.RenderMessage(channelID, msg)
+	payload.MessageThreadID = p.topic
+	return notifier.Ref{ChannelID: channelID}, p.print("sendMessage", payload)
+}
+
+// Update prints the step added to the timeline, the existing message isn't read in a dry run
+func (p *dryRunProvider) Update(_ context.Context, ref notifier.Ref, msg notifier.Message) (notifier.Ref, error) {
+	if p.channel != "slack" {
+		return ref, p.print("editMessageText (appended)", telegram.RenderUpdate(ref, msg))
+	}
+	return ref, p.print("chat.update (appended)", slack.RenderUpdate(ref, msg, p.blocks))
+}
+
+// Find prints the lookup and reports no message, so a dry run upsert prints the send
+func (p *dryRunProvider) Find(_ context.Context, channelID, key string) (notifier.Ref, bool, error) {
+	if p.channel == "slack" {
+		return notifier.Ref{}, false, p.print("conversations.history (find key)", map[string]string{"channel": channelID, "key": key})
+	}
+	return notifier.Ref{}, false, p.print("state file (find key)", map[string]string{"chat_id": channelID, "key": key})
+}
+
+func (p *dryRunProvider) Reply(_ context.Context, ref notifier.Ref, msg notifier.Message) (notifier.Ref, error) {
+	if p.channel == "slack" {
+		return ref, p.print("chat.postMessage", slack.RenderReply(ref, msg, p.blocks, p.broadcast))
+	}
+	payload := telegram.RenderReply(ref, msg)
+	payload.MessageThreadID = p.topic
+	return ref, p.print("sendMessage", payload)
+}
+
+func (p *dryRunProvider) React(_ context.Context, ref notifier.Ref, add, remove []string) error {
+	if p.channel != "slack" {
+		return p.print("setMessageReaction", map[string]any{"chat_id": ref.ChannelID, "message_id": ref.MessageID, "add": add, "remove": remove})
+	}
+	for _, name := range remove {
+		if err := p.print("reactions.remove", map[string]string{"channel": ref.ChannelID, "timestamp": ref.MessageID, "name": name}); err != nil {
+			return err
+		}
+	}
+	for _, name := range add {
+		if err := p.print("reactions.add", map[string]string{"channel": ref.ChannelID, "timestamp": ref.MessageID, "name": name}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *dryRunProvider) Pin(_ context.Context, ref notifier.Ref, exclusive bool) error {
+	if p.channel == "slack" {
+		return p.print("pins.add", map[string]any{"channel": ref.ChannelID, "timestamp": ref.MessageID, "unpin_previous": exclusive})
+	}
+	return p.print("pinChatMessage", map[string]any{"chat_id": ref.ChannelID, "message_id": ref.MessageID, "unpin_previous": exclusive})
+}
+
+func (p *dryRunProvider) Unpin(_ context.Context, ref notifier.Ref) error {
+	if p.channel == "slack" {
+		return p.print("pins.remove", map[string]string{"channel": ref.ChannelID, "timestamp": ref.MessageID})
+	}
+	return p.print("unpinChatMessage", map[string]string{"chat_id": ref.ChannelID, "message_id": ref.MessageID})
+}
+
+func (p *dryRunProvider) SetTopic(_ context.Context, channelID, topic string) error {
+	if p.channel == "slack" {
+		return p.print("conversations.setTopic", map[string]string{"channel": channelID, "topic": topic})
+	}
+	return p.print("setChatDescription", map[string]string{"chat_id": channelID, "description": topic})
+}
+
+func (p *dryRunProvider) Delete(_ context.Context, ref notifier.Ref) error {
+	if p.channel == "slack" {
+		return p.print("chat.delete", map[string]string{"channel": ref.ChannelID, "ts": ref.MessageID})
+	}
+	return p.print("deleteMessage", map[string]string{"chat_id": ref.ChannelID, "message_id": ref.MessageID})
+}
+
+// Cleanup prints the history read, nothing is listed or deleted in a dry run
+func (p *dryRunProvider) Cleanup(_ context.Context, channelID string, before time.Time) (int, error) {
+	if p.channel == "slack" {
+		return 0, p.print("conversations.history", map[string]string{"channel": channelID, "latest": before.Format(time.RFC3339)})
+	}
+	return 0, p.print("deleteMessage (state file)", map[string]string{"chat_id": channelID, "before": before.Format(time.RFC3339)})
+}
+
+func (p *dryRunProvider) Verify(context.Context, string) (string, error) {
+	return "Dry run: credentials were not checked", nil
+}
+
+// print writes the API method followed by its indented JSON payload
+func (p *dryRunProvider) print(method string, payload any) error {
+	data, err := json.MarshalIndent(payload, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to render %s payload- %s", p.channel, err.Error())
+	}
+	fmt.Fprintf(p.w, "%s %s\n", method, data)
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+)
+
+// githubEvent is the subset of the GITHUB_EVENT_PATH payload used for notifications
+// It covers push, pull_request, release and workflow_dispatch events
+type githubEvent struct {
+	Compare    string `json:"compare"`
+	HeadCommit *struct {
+		ID        string `json:"id"`
+		Message   string `json:"message"`
+		Timestamp string `json:"timestamp"`
+		Author    struct {
+			Name  string `json:"name"`
+			Email string `json:"email"`
+		} `json:"author"`
+	} `json:"head_commit"`
+	Pusher *struct {
+		Name string `json:"name"`
+	} `json:"pusher"`
+	PullRequest *struct {
+		Number  int    `json:"number"`
+		Title   string `json:"title"`
+		HTMLURL string `json:"html_url"`
+		Head    struct {
+			SHA string `json:"sha"`
+			Ref string `json:"ref"`
+		} `json:"head"`
+		User struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	} `json:"pull_request"`
+	Release *struct {
+		TagName string `json:"tag_name"`
+		HTMLURL string `json:"html_url"`
+	} `json:"release"`
+	Sender *struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+}
+
+// readGithubEvent loads and decodes the event payload at path
+func readGithubEvent(path string) (*githubEvent, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event payload- %s", err.Error())
+	}
+	var event githubEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("failed to parse event payload- %s", err.Error())
+	}
+	return &event, nil
+}
+
+// applyGithubEvent fills empty fields from the event payload, explicit inputs always win
+func applyGithubEvent(in *ActionInputs, event *githubEvent) {
+	if c := event.HeadCommit; c != nil {
+		setDefault(&in.CommitSha, c.ID)
+		setDefault(&in.CommitMsg, c.Message)
+		setDefault(&in.CommitTime, c.Timestamp)
+		setDefault(&in.Author, c.Author.Name)
+		setDefault(&in.AuthorEmail, c.Author.Email)
+	}
+	if event.Pusher != nil {
+		setDefault(&in.Pusher, event.Pusher.Name)
+	}
+	setDefault(&in.CompareURL, event.Compare)
+	if pr := event.PullRequest; pr != nil {
+		// GITHUB_SHA points to the merge commit, the head commit is what was pushed
+		setDefault(&in.CommitSha, pr.Head.SHA)
+		setDefault(&in.Author, pr.User.Login)
+		setDefault(&in.PRNumber, strconv.Itoa(pr.Number))
+		setDefault(&in.PRTitle, pr.Title)
+		setDefault(&in.PRURL, pr.HTMLURL)
+	}
+	if r := event.Release; r != nil {
+		setDefault(&in.ReleaseTag, r.TagName)
+		setDefault(&in.ReleaseURL, r.HTMLURL)
+	}
+	// workflow_dispatch has no pusher, the sender is who triggered the run
+	if event.Sender != nil {
+		setDefault(&in.Pusher, event.Sender.Login)
+	}
+}
+
+// discoverGithubEvent applies the payload at GITHUB_EVENT_PATH, if any
+func discoverGithubEvent(in *ActionInputs, getenv func(string) string) {
+	path := getenv("GITHUB_EVENT_PATH")
+	if path == "" {
+		return
+	}
+	event, err := readGithubEvent(path)
+	if err != nil {
+		slog.Warn("Failed to load GitHub event", slog.String("error", err.Error()))
+		return
+	}
+	applyGithubEvent(in, event)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// discoverGithubContext reads the GitHub Actions environment and event payload
+func discoverGithubContext(in *ActionInputs, getenv func(string) string) {
+	// The event payload carries richer details (commit message, author name) than the env vars
+	discoverGithubEvent(in, getenv)
+	setDefault(&in.CommitSha, getenv("GITHUB_SHA"))
+	// GITHUB_HEAD_REF is only set for pull requests, GITHUB_REF_NAME would be "<pr>/merge"
+	setDefault(&in.Branch, getenv("GITHUB_HEAD_REF"))
+	setDefault(&in.Branch, getenv("GITHUB_REF_NAME"))
+	setDefault(&in.Author, getenv("GITHUB_ACTOR"))
+	setDefault(&in.WorkflowName, getenv("GITHUB_WORKFLOW"))
+	setDefault(&in.Repository, getenv("GITHUB_REPOSITORY"))
+	setDefault(&in.RunID, getenv("GITHUB_RUN_ID"))
+	setDefault(&in.RunAttempt, getenv("GITHUB_RUN_ATTEMPT"))
+
+	server := getenv("GITHUB_SERVER_URL")
+	if server == "" {
+		server = "https://github.com"
+	}
+	if in.Repository == "" {
+		return
+	}
+	repoURL := fmt.Sprintf("%s/%s", server, in.Repository)
+	if in.RunID != "" {
+		runURL := fmt.Sprintf("%s/actions/runs/%s", repoURL, in.RunID)
+		if in.RunAttempt != "" {
+			runURL += "/attempts/" + in.RunAttempt
+		}
+		setDefault(&in.RunURL, runURL)
+	}
+	if in.CommitSha != "" {
+		setDefault(&in.CommitURL, fmt.Sprintf("%s/commit/%s", repoURL, in.CommitSha))
+	}
+	if base, head := getenv("GITHUB_BASE_REF"), getenv("GITHUB_HEAD_REF"); base != "" && head != "" {
+		setDefault(&in.CompareURL, fmt.Sprintf("%s/compare/%s...%s", repoURL, base, head))
+	}
+}
+
+// githubWarning prints a ::warning:: workflow command, the message is escaped as the runner expects
+func githubWarning(w io.Writer, msg string) {
+	msg = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(msg)
+	fmt.Fprintf(w, "::warning::%s\n", msg)
+}
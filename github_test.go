@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiscoverGithubContext(t *testing.T) {
+	env := map[string]string{
+		"GITHUB_ACTIONS":     "true",
+		"GITHUB_SHA":         "abc123",
+		"GITHUB_REF_NAME":    "main",
+		"GITHUB_ACTOR":       "octocat",
+		"GITHUB_WORKFLOW":    "CI",
+		"GITHUB_RUN_ID":      "42",
+		"GITHUB_RUN_ATTEMPT": "2",
+		"GITHUB_SERVER_URL":  "https://github.com",
+		"GITHUB_REPOSITORY":  "org/repo",
+	}
+	getenv := func(key string) string { return env[key] }
+
+	tests := []struct {
+		name     string
+		in       ActionInputs
+		expected ActionInputs
+	}{
+		{
+			name: "empty inputs are discovered",
+			in:   ActionInputs{},
+			expected: ActionInputs{
+				CommitSha:    "abc123",
+				Branch:       "main",
+				Author:       "octocat",
+				WorkflowName: "CI",
+				Repository:   "org/repo",
+				RunID:        "42",
+				RunAttempt:   "2",
+				RunURL:       "https://github.com/org/repo/actions/runs/42/attempts/2",
+				CommitURL:    "https://github.com/org/repo/commit/abc123",
+			},
+		},
+		{
+			name: "explicit inputs win",
+			in:   ActionInputs{CommitSha: "def456", Branch: "release", Author: "me"},
+			expected: ActionInputs{
+				CommitSha:    "def456",
+				Branch:       "release",
+				Author:       "me",
+				WorkflowName: "CI",
+				Repository:   "org/repo",
+				RunID:        "42",
+				RunAttempt:   "2",
+				RunURL:       "https://github.com/org/repo/actions/runs/42/attempts/2",
+				CommitURL:    "https://github.com/org/repo/commit/def456",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := tt.in
+			discoverGithubContext(&in, getenv)
+			if in != tt.expected {
+				t.Errorf("discoverGithubContext() = %+v, expected %+v", in, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDiscoverGithubContextPullRequest(t *testing.T) {
+	env := map[string]string{
+		"GITHUB_ACTIONS":    "true",
+		"GITHUB_REF_NAME":   "7/merge",
+		"GITHUB_HEAD_REF":   "feature",
+		"GITHUB_BASE_REF":   "main",
+		"GITHUB_REPOSITORY": "org/repo",
+	}
+	in := ActionInputs{}
+	discoverGithubContext(&in, func(key string) string { return env[key] })
+
+	if in.Branch != "feature" {
+		t.Errorf("Branch = %s, expected feature", in.Branch)
+	}
+	if in.CompareURL != "https://github.com/org/repo/compare/main...feature" {
+		t.Errorf("CompareURL = %s", in.CompareURL)
+	}
+}
+
+func TestDiscoverGithubEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		expected ActionInputs
+	}{
+		{
+			name: "push",
+			payload: `{
+				"compare": "https://github.com/org/repo/compare/aaa...bbb",
+				"head_commit": {
+					"id": "bbb",
+					"message": "fix bug",
+					"timestamp": "2023-01-01T10:00:00Z",
+					"author": {"name": "Jane", "email": "jane@example.com"}
+				},
+				"pusher": {"name": "jane"}
+			}`,
+			expected: ActionInputs{
+				CommitSha:   "bbb",
+				CommitMsg:   "fix bug",
+				CommitTime:  "2023-01-01T10:00:00Z",
+				Author:      "Jane",
+				AuthorEmail: "jane@example.com",
+				Pusher:      "jane",
+				CompareURL:  "https://github.com/org/repo/compare/aaa...bbb",
+			},
+		},
+		{
+			name: "pull_request",
+			payload: `{
+				"pull_request": {
+					"number": 12,
+					"title": "Add feature",
+					"html_url": "https://github.com/org/repo/pull/12",
+					"head": {"sha": "ccc", "ref": "feature"},
+					"user": {"login": "dev"}
+				},
+				"sender": {"login": "dev"}
+			}`,
+			expected: ActionInputs{
+				CommitSha: "ccc",
+				Author:    "dev",
+				Pusher:    "dev",
+				PRNumber:  "12",
+				PRTitle:   "Add feature",
+				PRURL:     "https://github.com/org/repo/pull/12",
+			},
+		},
+		{
+			name: "release",
+			payload: `{
+				"release": {"tag_name": "v1.2.0", "html_url": "https://github.com/org/repo/releases/tag/v1.2.0"},
+				"sender": {"login": "maintainer"}
+			}`,
+			expected: ActionInputs{
+				Pusher:     "maintainer",
+				ReleaseTag: "v1.2.0",
+				ReleaseURL: "https://github.com/org/repo/releases/tag/v1.2.0",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "event.json")
+			if err := os.WriteFile(path, []byte(tt.payload), 0644); err != nil {
+				t.Fatal(err)
+			}
+			in := ActionInputs{}
+			discoverGithubEvent(&in, func(key string) string {
+				if key == "GITHUB_EVENT_PATH" {
+					return path
+				}
+				return ""
+			})
+			if in != tt.expected {
+				t.Errorf("discoverGithubEvent() = %+v, expected %+v", in, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGithubWarning(t *testing.T) {
+	var out bytes.Buffer
+	githubWarning(&out, "100% failed\nretry later")
+	expected := "::warning::100%25 failed%0Aretry later\n"
+	if out.String() != expected {
+		t.Errorf("githubWarning() = %q, expected %q", out.String(), expected)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// discoverGitlabContext reads the GitLab CI predefined variables
+func discoverGitlabContext(in *ActionInputs, getenv func(string) string) {
+	setDefault(&in.CommitSha, getenv("CI_COMMIT_SHA"))
+	setDefault(&in.Branch, getenv("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME"))
+	setDefault(&in.Branch, getenv("CI_COMMIT_REF_NAME"))
+	name, email := splitAuthor(getenv("CI_COMMIT_AUTHOR"))
+	setDefault(&in.Author, name)
+	setDefault(&in.AuthorEmail, email)
+	setDefault(&in.Pusher, getenv("GITLAB_USER_LOGIN"))
+	setDefault(&in.WorkflowName, getenv("CI_PIPELINE_NAME"))
+	setDefault(&in.WorkflowName, getenv("CI_JOB_NAME"))
+	setDefault(&in.CommitMsg, getenv("CI_COMMIT_TITLE"))
+	setDefault(&in.CommitTime, getenv("CI_COMMIT_TIMESTAMP"))
+	setDefault(&in.Repository, getenv("CI_PROJECT_PATH"))
+	setDefault(&in.RunID, getenv("CI_PIPELINE_ID"))
+	setDefault(&in.RunURL, getenv("CI_PIPELINE_URL"))
+	setDefault(&in.ReleaseTag, getenv("CI_COMMIT_TAG"))
+	// MESSAGE_ID comes from a previous job's dotenv report
+	setDefault(&in.MsgID, getenv("MESSAGE_ID"))
+
+	if iid := getenv("CI_MERGE_REQUEST_IID"); iid != "" {
+		setDefault(&in.PRNumber, iid)
+		setDefault(&in.PRTitle, getenv("CI_MERGE_REQUEST_TITLE"))
+		if mrProject := getenv("CI_MERGE_REQUEST_PROJECT_URL"); mrProject != "" {
+			setDefault(&in.PRURL, fmt.Sprintf("%s/-/merge_requests/%s", mrProject, iid))
+		}
+	}
+
+	projectURL := getenv("CI_PROJECT_URL")
+	if projectURL == "" {
+		return
+	}
+	if in.CommitSha != "" {
+		setDefault(&in.CommitURL, fmt.Sprintf("%s/-/commit/%s", projectURL, in.CommitSha))
+	}
+	if target, source := getenv("CI_MERGE_REQUEST_TARGET_BRANCH_NAME"), getenv("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME"); target != "" && source != "" {
+		setDefault(&in.CompareURL, fmt.Sprintf("%s/-/compare/%s...%s", projectURL, target, source))
+	}
+	// CI_COMMIT_BEFORE_SHA is all zeros for new branches and merge request pipelines
+	if before := getenv("CI_COMMIT_BEFORE_SHA"); before != "" && strings.Trim(before, "0") != "" && in.CommitSha != "" {
+		setDefault(&in.CompareURL, fmt.Sprintf("%s/-/compare/%s...%s", projectURL, before, in.CommitSha))
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io"
+	"os"
+	"testing"
+)
+
+func TestDiscoverGitlabContext(t *testing.T) {
+	env := map[string]string{
+		"GITLAB_CI":            "true",
+		"CI_COMMIT_SHA":        "abc123",
+		"CI_COMMIT_BEFORE_SHA": "aaa000",
+		"CI_COMMIT_REF_NAME":   "main",
+		"CI_COMMIT_AUTHOR":     "Jane Doe <jane@example.com>",
+		"CI_COMMIT_TITLE":      "fix bug",
+		"CI_COMMIT_TIMESTAMP":  "2023-01-01T10:00:00+00:00",
+		"CI_JOB_NAME":          "deploy",
+		"CI_PIPELINE_ID":       "99",
+		"CI_PIPELINE_URL":      "https://gitlab.com/org/repo/-/pipelines/99",
+		"CI_PROJECT_PATH":      "org/repo",
+		"CI_PROJECT_URL":       "https://gitlab.com/org/repo",
+		"GITLAB_USER_LOGIN":    "jane",
+		"MESSAGE_ID":           "1700000000.000100",
+	}
+	in := ActionInputs{Branch: "explicit"}
+	discoverGitlabContext(&in, func(key string) string { return env[key] })
+
+	expected := ActionInputs{
+		MsgID:        "1700000000.000100",
+		CommitSha:    "abc123",
+		Branch:       "explicit",
+		Author:       "Jane Doe",
+		AuthorEmail:  "jane@example.com",
+		Pusher:       "jane",
+		WorkflowName: "deploy",
+		CommitMsg:    "fix bug",
+		CommitTime:   "2023-01-01T10:00:00+00:00",
+		Repository:   "org/repo",
+		RunID:        "99",
+		RunURL:       "https://gitlab.com/org/repo/-/pipelines/99",
+		CommitURL:    "https://gitlab.com/org/repo/-/commit/abc123",
+		CompareURL:   "https://gitlab.com/org/repo/-/compare/aaa000...abc123",
+	}
+	if in != expected {
+		t.Errorf("discoverGitlabContext() = %+v, expected %+v", in, expected)
+	}
+}
+
+func TestDotenvReportSink(t *testing.T) {
+	path := t.TempDir() + "/notifier.env"
+	sink := dotenvReportSink(ActionInputs{DotenvFile: path}, func(string) string { return "" }, io.Discard)
+
+	if err := sink.Write(map[string]string{"message_id": "123", "channel_id": "C1"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "CHANNEL_ID=C1\nMESSAGE_ID=123\n"
+	if string(data) != expected {
+		t.Errorf("dotenv = %q, expected %q", string(data), expected)
+	}
+}
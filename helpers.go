@@ -1,117 +1,183 @@
 package main
 
 import (
+	"cicd-notifier/pkg/notifier"
 	"fmt"
-	"log/slog"
-	"os"
+	"io"
 	"strings"
 )
 
-var outputs = make(map[string]string)
-
-// addOutput adds a key-value pair to the outputs map
-func addOutput(key, value string) {
-	outputs[key] = value
-}
-
 // readInputs reads all INPUT_* environment variables and returns a map
+// NOTIFIER_* (GitLab and others) and PLUGIN_* (Drone/Woodpecker plugin settings) are read as well,
+// later prefixes win: NOTIFIER_ < PLUGIN_ < INPUT_
+// env uses the os.Environ format
 // To add new params, just use inputs["new_param_name"] in your code
-func readInputs() map[string]string {
+func readInputs(env []string) map[string]string {
 	inputs := make(map[string]string)
-	for _, env := range os.Environ() {
-		if strings.HasPrefix(env, "INPUT_") {
-			parts := strings.SplitN(env, "=", 2)
-			if len(parts) == 2 {
-				key := strings.ToLower(strings.TrimPrefix(parts[0], "INPUT_"))
-				value := parts[1]
-				inputs[key] = value
+	for _, prefix := range []string{"NOTIFIER_", "PLUGIN_", "INPUT_"} {
+		for _, kv := range env {
+			if strings.HasPrefix(kv, prefix) {
+				parts := strings.SplitN(kv, "=", 2)
+				if len(parts) == 2 {
+					key := strings.ToLower(strings.TrimPrefix(parts[0], prefix))
+					value := parts[1]
+					inputs[key] = value
+				}
 			}
 		}
 	}
 	return inputs
 }
 
-// setOutputs writes the global outputs map to GITHUB_OUTPUT or prints to console
-// To add new outputs, use addOutput(key, value)
-func setOutputs() {
-	outputFile := os.Getenv("GITHUB_OUTPUT")
-	if outputFile == "" {
-		// Not running in GitHub Actions, print to console
-		fmt.Println("Outputs:")
-		for key, value := range outputs {
-			fmt.Printf("%s=%s\n", key, value)
+// lookupEnv returns a getenv func over an os.Environ formatted slice
+func lookupEnv(env []string) func(string) string {
+	vars := make(map[string]string, len(env))
+	for _, kv := range env {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			vars[key] = value
 		}
-		return
 	}
+	return func(key string) string { return vars[key] }
+}
 
-	file, err := os.OpenFile(outputFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		fmt.Printf("Error opening output file: %v\n", err)
-		return
+// splitList splits a comma separated input, empty entries are dropped
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
-	defer file.Close()
+	return items
+}
 
-	for key, value := range outputs {
-		fmt.Fprintf(file, "%s=%s\n", key, value)
+// setOutputs writes outputs to the output sink of the detected CI environment
+func setOutputs(cfg ActionInputs, outputs map[string]string, getenv func(string) string, stdout io.Writer) error {
+	sink := detectCIEnvironment(getenv).sink(cfg, getenv, stdout)
+	if err := sink.Write(outputs); err != nil {
+		return fmt.Errorf("failed to write outputs- %s", err.Error())
 	}
+	return nil
 }
 
-func validateInputs() {
-	if ParsedInputs.Action != "send" && ParsedInputs.Action != "update" {
-		slog.Error("Wrong Operation", "action", ParsedInputs.Action)
-		os.Exit(1)
+// validateInputs checks the required inputs for the selected action
+func validateInputs(cfg ActionInputs) error {
+	if !isCommand(cfg.Action) {
+		return fmt.Errorf("%w: wrong operation %q", errInvalidConfig, cfg.Action)
+	}
+	// The hooks run for every use of the action, they only need inputs with auto_finalize
+	if isHook(cfg.Action) && !cfg.AutoFinalize {
+		return nil
 	}
-	if ParsedInputs.Message == "" {
-		slog.Error("message is required")
-		os.Exit(1)
+	if cfg.Channel == "" {
+		return fmt.Errorf("%w: channel is required", errInvalidConfig)
 	}
-	if ParsedInputs.ApiKey == "" {
-		slog.Error("ApiKey is required")
-		os.Exit(1)
+	if channel := strings.ToLower(cfg.Channel); channel != "slack" && channel != "telegram" {
+		return fmt.Errorf("%w: unsupported channel %q", errInvalidConfig, cfg.Channel)
 	}
-	if ParsedInputs.Channel == "" {
-		slog.Error("channel is required")
-		os.Exit(1)
+	if _, err := notifier.ParseStatus(cfg.Status); err != nil {
+		return fmt.Errorf("%w: %s", errInvalidConfig, err.Error())
 	}
-	if ParsedInputs.ChannelId == "" {
-		slog.Error("channelId is required")
-		os.Exit(1)
+	if cfg.Action == "preview" {
+		if cfg.Message == "" && cfg.StepName == "" {
+			return fmt.Errorf("%w: message is required", errInvalidConfig)
+		}
+		return nil
 	}
-	if ParsedInputs.Action == "" {
-		slog.Error("action is missing")
-		os.Exit(1)
+	if cfg.ApiKey == "" {
+		return fmt.Errorf("%w: api_key is required", errInvalidConfig)
 	}
-	if ParsedInputs.Action == "update" && ParsedInputs.MsgID == "" {
-		slog.Error("Missing MsgId for Action-Update")
-		os.Exit(1)
+	if cfg.Action == "verify" {
+		return nil
 	}
-	fmt.Printf("%v\n", ParsedInputs)
-}
-
-func templateCommitInfo() string {
-	msg := "📦 *Github Workflow*\n\n"
-
-	if ParsedInputs.CommitSha != "" {
-		msg += fmt.Sprintf("📌 *Commit:* `%s`\n", ParsedInputs.CommitSha)
+	// A step update may only change the status
+	if (cfg.Action == "send" || cfg.Action == "update" || cfg.Action == "upsert" || cfg.Action == "reply") && cfg.Message == "" && cfg.StepName == "" {
+		return fmt.Errorf("%w: message is required", errInvalidConfig)
+	}
+	if cfg.ChannelId == "" {
+		return fmt.Errorf("%w: channel_id is required", errInvalidConfig)
+	}
+	if cfg.UpdateMode != "" && cfg.UpdateMode != "edit" && cfg.UpdateMode != "thread" {
+		return fmt.Errorf("%w: update_mode must be edit or thread, got %q", errInvalidConfig, cfg.UpdateMode)
+	}
+	if cfg.Action == "react" && len(splitList(cfg.ReactAdd)) == 0 && len(splitList(cfg.ReactRemove)) == 0 {
+		return fmt.Errorf("%w: reactions or remove_reactions is required for react", errInvalidConfig)
 	}
-	if ParsedInputs.Branch != "" {
-		msg += fmt.Sprintf("🔖 *Branch:* `%s`\n", ParsedInputs.Branch)
+	if (cfg.Action == "upsert" || cfg.Action == "aggregate") && cfg.Key == "" {
+		return fmt.Errorf("%w: key is required for %s", errInvalidConfig, cfg.Action)
 	}
-	if ParsedInputs.WorkflowName != "" {
-		msg += fmt.Sprintf("🛠️ *Workflow:* `%s`\n", ParsedInputs.WorkflowName)
+	if cfg.Action == "aggregate" && (cfg.StepName == "" || cfg.Status == "") {
+		return fmt.Errorf("%w: step_name and status of the matrix leg are required for aggregate", errInvalidConfig)
 	}
-	if ParsedInputs.CommitMsg != "" {
-		msg += fmt.Sprintf("📝 *Message:* %s\n", ParsedInputs.CommitMsg)
+	if cfg.Action == "cleanup" && cfg.OlderThan <= 0 {
+		return fmt.Errorf("%w: older_than is required for cleanup", errInvalidConfig)
 	}
-	if ParsedInputs.Author != "" {
-		msg += fmt.Sprintf("👤 *Author:* %s\n", ParsedInputs.Author)
+	// Telegram messages can only be edited, found and cleaned up with their timeline in state_file
+	if strings.EqualFold(cfg.Channel, "telegram") && cfg.StateFile == "" {
+		switch {
+		case cfg.AutoFinalize:
+			// post edits the started message
+			return fmt.Errorf("%w: state_file is required for auto_finalize with telegram", errInvalidConfig)
+		case cfg.Action == "update" && cfg.UpdateMode != "thread", cfg.Action == "upsert", cfg.Action == "aggregate", cfg.Action == "cleanup":
+			return fmt.Errorf("%w: state_file is required for %s with telegram", errInvalidConfig, cfg.Action)
+		}
+	}
+	if cfg.Action == "set_topic" && topic(cfg) == "" {
+		return fmt.Errorf("%w: message, image_tag or commit_sha is required for set_topic", errInvalidConfig)
+	}
+	switch cfg.Action {
+	case "update", "reply", "react", "pin", "unpin", "delete":
+		if cfg.MsgID == "" {
+			return fmt.Errorf("%w: msg_id is required for %s", errInvalidConfig, cfg.Action)
+		}
+	}
+	return nil
+}
+
+// discoverMatrix defaults the aggregate key to the workflow run, so all legs of a run (and their
+// re-runs) report into one message, and the leg link to the run
+func discoverMatrix(cfg *ActionInputs) {
+	if cfg.Key == "" && cfg.RunID != "" {
+		cfg.Key = "matrix/" + cfg.Repository + "/" + cfg.RunID
 	}
-	if ParsedInputs.ImageTag != "" {
-		msg += fmt.Sprintf("🐳 *Image Tag:* %s\n", ParsedInputs.ImageTag)
+	if cfg.StepURL == "" {
+		cfg.StepURL = cfg.RunURL
 	}
-	if ParsedInputs.CommitTime != "" {
-		msg += fmt.Sprintf("🕗 *Commit Time:* %s\n", ParsedInputs.CommitTime)
+}
+
+// topic returns the set_topic text: the message, or the deployed version from the commit info
+func topic(cfg ActionInputs) string {
+	if cfg.Message != "" {
+		return cfg.Message
+	}
+	return notifier.FormatTopic(commitInfo(cfg))
+}
+
+// templateCommitInfo renders the commit info header for cfg
+func templateCommitInfo(cfg ActionInputs) string {
+	return notifier.FormatCommitInfo(commitInfo(cfg))
+}
+
+// commitInfo maps the parsed inputs to the notifier commit model
+func commitInfo(cfg ActionInputs) *notifier.CommitInfo {
+	return &notifier.CommitInfo{
+		CommitSha:    cfg.CommitSha,
+		Branch:       cfg.Branch,
+		WorkflowName: cfg.WorkflowName,
+		CommitMsg:    cfg.CommitMsg,
+		Author:       cfg.Author,
+		AuthorEmail:  cfg.AuthorEmail,
+		Pusher:       cfg.Pusher,
+		ImageTag:     cfg.ImageTag,
+		CommitTime:   cfg.CommitTime,
+		RunURL:       cfg.RunURL,
+		CommitURL:    cfg.CommitURL,
+		CompareURL:   cfg.CompareURL,
+		PRNumber:     cfg.PRNumber,
+		PRTitle:      cfg.PRTitle,
+		PRURL:        cfg.PRURL,
+		ReleaseTag:   cfg.ReleaseTag,
+		ReleaseURL:   cfg.ReleaseURL,
+		System:       cfg.CISystem,
 	}
-	msg += "\n"
-	return msg
 }
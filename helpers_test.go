@@ -1,9 +1,12 @@
 package main
 
 import (
+	"cicd-notifier/pkg/notifier"
+	"errors"
+	"flag"
+	"io"
 	"os"
 	"strconv"
-	"strings"
 	"testing"
 )
 
@@ -41,7 +44,7 @@ func TestReadInputs(t *testing.T) {
 		os.Unsetenv("INPUT_MSGID")
 	}()
 
-	inputs := readInputs()
+	inputs := readInputs(os.Environ())
 
 	expected := map[string]string{
 		"action":          "send",
@@ -69,23 +72,28 @@ func TestReadInputs(t *testing.T) {
 	}
 }
 
-func TestAddOutput(t *testing.T) {
-	// Reset global outputs
-	outputs = make(map[string]string)
+func TestAddIDs(t *testing.T) {
+	outputs := make(map[string]string)
 
-	addOutput("key1", "value1")
-	addOutput("key2", "value2")
+	addIDs(outputs, notifier.Ref{ChannelID: "C123", MessageID: "1700000000.000100"})
 
-	if outputs["key1"] != "value1" {
-		t.Errorf("addOutput() key1 = %s, expected value1", outputs["key1"])
+	if outputs["message_id"] != "1700000000.000100" {
+		t.Errorf("addIDs() message_id = %s, expected 1700000000.000100", outputs["message_id"])
 	}
-	if outputs["key2"] != "value2" {
-		t.Errorf("addOutput() key2 = %s, expected value2", outputs["key2"])
+	if outputs["channel_id"] != "C123" {
+		t.Errorf("addIDs() channel_id = %s, expected C123", outputs["channel_id"])
+	}
+
+	// Providers without a channel ID output
+	outputs = make(map[string]string)
+	addIDs(outputs, notifier.Ref{MessageID: "42"})
+	if _, ok := outputs["channel_id"]; ok {
+		t.Errorf("addIDs() channel_id set for empty channel ID")
 	}
 }
 
 func TestTemplateCommitInfo(t *testing.T) {
-	ParsedInputs = ActionInputs{
+	ParsedInputs := ActionInputs{
 		AddCommitInfo: true,
 		CommitSha:     "abc123",
 		Branch:        "main",
@@ -96,7 +104,7 @@ func TestTemplateCommitInfo(t *testing.T) {
 		CommitTime:    "2023-01-01",
 	}
 
-	result := templateCommitInfo()
+	result := templateCommitInfo(ParsedInputs)
 
 	expected := "📦 *Github Workflow*\n\n📌 *Commit:* `abc123`\n🔖 *Branch:* `main`\n🛠️ *Workflow:* `CI`\n📝 *Message:* fix bug\n👤 *Author:* user\n🐳 *Image Tag:* v1.0\n🕗 *Commit Time:* 2023-01-01\n\n"
 	if result != expected {
@@ -137,10 +145,10 @@ func TestReadInputsTemplateCommitInfoAndAddMessage(t *testing.T) {
 	}()
 
 	// Read inputs
-	inputs := readInputs()
+	inputs := readInputs(os.Environ())
 
 	// Simulate init logic
-	ParsedInputs = ActionInputs{
+	ParsedInputs := ActionInputs{
 		Action:    inputs["action"],
 		Message:   inputs["message"],
 		ApiKey:    inputs["api_key"],
@@ -167,7 +175,7 @@ func TestReadInputsTemplateCommitInfoAndAddMessage(t *testing.T) {
 	// Template commit info if enabled
 	var commitMsg string
 	if ParsedInputs.AddCommitInfo {
-		commitMsg = templateCommitInfo()
+		commitMsg = templateCommitInfo(ParsedInputs)
 	} else {
 		commitMsg = "📦 *Github Workflow*\n\n"
 	}
@@ -199,29 +207,7 @@ func TestSlackSendFlow(t *testing.T) {
 	}()
 
 	// Simulate init logic
-	inputs := readInputs()
-	ParsedInputs = ActionInputs{
-		Action:       strings.ToLower(inputs["action"]),
-		MsgID:        inputs["msgid"],
-		Message:      inputs["message"],
-		ApiKey:       inputs["api_key"],
-		ChannelId:    inputs["channel_id"],
-		ImageTag:     inputs["image_tag"],
-		CommitSha:    inputs["commit_sha"],
-		Branch:       inputs["branch"],
-		Author:       inputs["author"],
-		CommitTime:   inputs["commit_time"],
-		CommitMsg:    inputs["commit_msg"],
-		WorkflowName: inputs["workflow_name"],
-	}
-	if channelStr := inputs["channel"]; channelStr != "" {
-		ParsedInputs.Channel = strings.TrimSpace(channelStr)
-	}
-	if addCommitStr := inputs["add_commit_info"]; addCommitStr != "" {
-		if parsed, err := strconv.ParseBool(addCommitStr); err == nil {
-			ParsedInputs.AddCommitInfo = parsed
-		}
-	}
+	ParsedInputs := parseInputs(readInputs(os.Environ()), lookupEnv([]string{"GITHUB_ACTIONS=true"}))
 
 	// Check ParsedInputs
 	if ParsedInputs.Action != "send" {
@@ -246,7 +232,7 @@ func TestSlackSendFlow(t *testing.T) {
 	// Simulate the send flow (without API call)
 	var commitMsg string
 	if ParsedInputs.AddCommitInfo {
-		commitMsg = templateCommitInfo()
+		commitMsg = templateCommitInfo(ParsedInputs)
 	} else {
 		commitMsg = "📦 *Github Workflow*\n\n"
 	}
@@ -264,7 +250,7 @@ func TestSlackUpdateFlow(t *testing.T) {
 	os.Setenv("INPUT_MESSAGE", "Updated message")
 	os.Setenv("INPUT_API_KEY", "test_slack_key")
 	os.Setenv("INPUT_CHANNEL_ID", "#test")
-	os.Setenv("INPUT_MSGID", "123456")
+	os.Setenv("INPUT_MSG_ID", "123456")
 	os.Setenv("INPUT_CHANNEL", "slack")
 	os.Setenv("INPUT_ADD_COMMIT_INFO", "true")
 	os.Setenv("INPUT_COMMIT_SHA", "abc123")
@@ -279,7 +265,7 @@ func TestSlackUpdateFlow(t *testing.T) {
 		os.Unsetenv("INPUT_MESSAGE")
 		os.Unsetenv("INPUT_API_KEY")
 		os.Unsetenv("INPUT_CHANNEL_ID")
-		os.Unsetenv("INPUT_MSGID")
+		os.Unsetenv("INPUT_MSG_ID")
 		os.Unsetenv("INPUT_CHANNEL")
 		os.Unsetenv("INPUT_ADD_COMMIT_INFO")
 		os.Unsetenv("INPUT_COMMIT_SHA")
@@ -292,29 +278,7 @@ func TestSlackUpdateFlow(t *testing.T) {
 	}()
 
 	// Simulate init logic
-	inputs := readInputs()
-	ParsedInputs = ActionInputs{
-		Action:       strings.ToLower(inputs["action"]),
-		MsgID:        inputs["msgid"],
-		Message:      inputs["message"],
-		ApiKey:       inputs["api_key"],
-		ChannelId:    inputs["channel_id"],
-		ImageTag:     inputs["image_tag"],
-		CommitSha:    inputs["commit_sha"],
-		Branch:       inputs["branch"],
-		Author:       inputs["author"],
-		CommitTime:   inputs["commit_time"],
-		CommitMsg:    inputs["commit_msg"],
-		WorkflowName: inputs["workflow_name"],
-	}
-	if channelStr := inputs["channel"]; channelStr != "" {
-		ParsedInputs.Channel = strings.TrimSpace(channelStr)
-	}
-	if addCommitStr := inputs["add_commit_info"]; addCommitStr != "" {
-		if parsed, err := strconv.ParseBool(addCommitStr); err == nil {
-			ParsedInputs.AddCommitInfo = parsed
-		}
-	}
+	ParsedInputs := parseInputs(readInputs(os.Environ()), lookupEnv([]string{"GITHUB_ACTIONS=true"}))
 
 	// Check ParsedInputs
 	if ParsedInputs.Action != "update" {
@@ -330,7 +294,7 @@ func TestSlackUpdateFlow(t *testing.T) {
 	// Simulate the update flow
 	var commitMsg string
 	if ParsedInputs.AddCommitInfo {
-		commitMsg = templateCommitInfo()
+		commitMsg = templateCommitInfo(ParsedInputs)
 	} else {
 		commitMsg = "📦 *Github Workflow*\n\n"
 	}
@@ -360,29 +324,7 @@ func TestTelegramSendFlow(t *testing.T) {
 	}()
 
 	// Simulate init logic
-	inputs := readInputs()
-	ParsedInputs = ActionInputs{
-		Action:       strings.ToLower(inputs["action"]),
-		MsgID:        inputs["msgid"],
-		Message:      inputs["message"],
-		ApiKey:       inputs["api_key"],
-		ChannelId:    inputs["channel_id"],
-		ImageTag:     inputs["image_tag"],
-		CommitSha:    inputs["commit_sha"],
-		Branch:       inputs["branch"],
-		Author:       inputs["author"],
-		CommitTime:   inputs["commit_time"],
-		CommitMsg:    inputs["commit_msg"],
-		WorkflowName: inputs["workflow_name"],
-	}
-	if channelStr := inputs["channel"]; channelStr != "" {
-		ParsedInputs.Channel = strings.TrimSpace(channelStr)
-	}
-	if addCommitStr := inputs["add_commit_info"]; addCommitStr != "" {
-		if parsed, err := strconv.ParseBool(addCommitStr); err == nil {
-			ParsedInputs.AddCommitInfo = parsed
-		}
-	}
+	ParsedInputs := parseInputs(readInputs(os.Environ()), lookupEnv([]string{"GITHUB_ACTIONS=true"}))
 
 	// Check ParsedInputs
 	if ParsedInputs.Channel != "telegram" {
@@ -398,7 +340,7 @@ func TestTelegramSendFlow(t *testing.T) {
 	// Simulate the send flow
 	var commitMsg string
 	if ParsedInputs.AddCommitInfo {
-		commitMsg = templateCommitInfo()
+		commitMsg = templateCommitInfo(ParsedInputs)
 	} else {
 		commitMsg = "📦 *Github Workflow*\n\n"
 	}
@@ -409,3 +351,78 @@ func TestTelegramSendFlow(t *testing.T) {
 		t.Errorf("Final message = %q, expected %q", finalMessage, expectedMessage)
 	}
 }
+
+func TestReadInputsNotifierPrefix(t *testing.T) {
+	os.Setenv("NOTIFIER_MESSAGE", "from plain env")
+	os.Setenv("NOTIFIER_CHANNEL", "telegram")
+	os.Setenv("INPUT_CHANNEL", "slack")
+	defer func() {
+		os.Unsetenv("NOTIFIER_MESSAGE")
+		os.Unsetenv("NOTIFIER_CHANNEL")
+		os.Unsetenv("INPUT_CHANNEL")
+	}()
+
+	inputs := readInputs(os.Environ())
+
+	if inputs["message"] != "from plain env" {
+		t.Errorf("readInputs() message = %s, expected from plain env", inputs["message"])
+	}
+	if inputs["channel"] != "slack" {
+		t.Errorf("readInputs() channel = %s, expected INPUT_CHANNEL to win", inputs["channel"])
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name            string
+		args            []string
+		expectedCommand string
+		expectedFlags   map[string]string
+		expectedExit    int
+	}{
+		{
+			name:            "command with flags",
+			args:            []string{"send", "--channel=slack", "-message", "hi"},
+			expectedCommand: "send",
+			expectedFlags:   map[string]string{"action": "send", "channel": "slack", "message": "hi"},
+		},
+		{
+			name:          "flags only keeps GitHub Action mode",
+			args:          []string{"--msg_id=123"},
+			expectedFlags: map[string]string{"msg_id": "123"},
+		},
+		{name: "unknown command", args: []string{"publish"}, expectedExit: exitUsage},
+		{name: "unknown flag", args: []string{"send", "--unknown=1"}, expectedExit: exitUsage},
+		{name: "help", args: []string{"--help"}, expectedExit: exitOK},
+		{name: "help command", args: []string{"help"}, expectedExit: exitOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command, flags, err := parseArgs(tt.args, io.Discard)
+			if tt.expectedFlags == nil {
+				if err == nil {
+					t.Fatalf("parseArgs() expected error")
+				}
+				if isHelp := errors.Is(err, flag.ErrHelp); isHelp != (tt.expectedExit == exitOK) {
+					t.Errorf("parseArgs() error = %v, expected exit code %d", err, tt.expectedExit)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseArgs() error = %v", err)
+			}
+			if command != tt.expectedCommand {
+				t.Errorf("parseArgs() command = %s, expected %s", command, tt.expectedCommand)
+			}
+			if len(flags) != len(tt.expectedFlags) {
+				t.Errorf("parseArgs() flags = %v, expected %v", flags, tt.expectedFlags)
+			}
+			for key, value := range tt.expectedFlags {
+				if flags[key] != value {
+					t.Errorf("parseArgs() %s = %s, expected %s", key, flags[key], value)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"cicd-notifier/pkg/notifier"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// hookCommands maps the names the binary is linked as in the image to the Docker
+// action pre-entrypoint/post-entrypoint commands, entrypoints can't take arguments
+var hookCommands = map[string]string{
+	"notifier-pre":  "pre",
+	"notifier-post": "post",
+}
+
+// hookArgs prepends the hook command when the binary was started through a hook link
+func hookArgs(argv0 string, args []string) []string {
+	if command, ok := hookCommands[filepath.Base(argv0)]; ok {
+		return append([]string{command}, args...)
+	}
+	return args
+}
+
+// isHook reports whether action is a pre/post entrypoint command
+func isHook(action string) bool {
+	return action == "pre" || action == "post"
+}
+
+// discoverHookState reads the message saved by pre from the STATE_ variables GitHub passes to later steps
+func discoverHookState(in *ActionInputs, getenv func(string) string) {
+	if !in.AutoFinalize {
+		return
+	}
+	setDefault(&in.MsgID, getenv("STATE_message_id"))
+	// Slack returns the channel ID for a "#name" channel, it is what update/delete need
+	if channelID := getenv("STATE_channel_id"); channelID != "" {
+		in.ChannelId = channelID
+	}
+}
+
+// jobStep is the step name pre and post track the job under
+func jobStep(cfg ActionInputs, getenv func(string) string) string {
+	if cfg.StepName != "" {
+		return cfg.StepName
+	}
+	if job := getenv("GITHUB_JOB"); job != "" {
+		return job
+	}
+	return "job"
+}
+
+// startJob sends the started message and saves its IDs to GITHUB_STATE for post
+func startJob(ctx context.Context, n *notifier.Notifier, cfg ActionInputs, getenv func(string) string) error {
+	msg := message(cfg)
+	if msg.Text == "" {
+		msg.Text = "Job started"
+	}
+	msg.Step, msg.Status = jobStep(cfg, getenv), notifier.StatusRunning
+	ref, err := n.Send(ctx, msg)
+	if err != nil {
+		return fmt.Errorf("failed to post %s message- %w", cfg.Channel, err)
+	}
+	state := make(map[string]string)
+	addIDs(state, ref)
+	path := getenv("GITHUB_STATE")
+	if path == "" {
+		slog.Warn("GITHUB_STATE is not set, post can't finalize the message")
+		return nil
+	}
+	return fileSink{path: path}.Write(state)
+}
+
+// finishJob updates the started message with the job outcome
+func finishJob(ctx context.Context, n *notifier.Notifier, cfg ActionInputs, getenv func(string) string, outputs map[string]string) error {
+	status, err := githubJobStatus(ctx, cfg.GithubToken, getenv)
+	text := jobOutcome[status]
+	if err != nil {
+		slog.Warn("Failed to get the job status", slog.String("error", err.Error()))
+		text = "Job finished, status unknown"
+	}
+	ref, err := n.Update(ctx, cfg.MsgID, notifier.Message{Text: text, Step: jobStep(cfg, getenv), Status: status})
+	if err != nil {
+		return fmt.Errorf("failed to update %s message- %w", cfg.Channel, err)
+	}
+	addIDs(outputs, ref)
+	return nil
+}
+
+var jobOutcome = map[notifier.Status]string{
+	notifier.StatusSuccess:   "Job succeeded",
+	notifier.StatusFailure:   "Job failed",
+	notifier.StatusCancelled: "Job cancelled",
+}
+
+// githubJob is the subset of the workflow jobs API response used to find the job outcome
+type githubJob struct {
+	Status     string `json:"status"`
+	RunnerName string `json:"runner_name"`
+	Steps      []struct {
+		Name       string `json:"name"`
+		Conclusion string `json:"conclusion"`
+	} `json:"steps"`
+}
+
+// githubJobStatus derives the outcome of the running job from the conclusions of its completed steps.
+// The job itself is still in progress while post runs, so the step conclusions are all there is.
+func githubJobStatus(ctx context.Context, token string, getenv func(string) string) (notifier.Status, error) {
+	api := getenv("GITHUB_API_URL")
+	if api == "" {
+		api = "https://api.github.com"
+	}
+	repo, runID, attempt := getenv("GITHUB_REPOSITORY"), getenv("GITHUB_RUN_ID"), getenv("GITHUB_RUN_ATTEMPT")
+	if repo == "" || runID == "" {
+		return "", fmt.Errorf("GITHUB_REPOSITORY and GITHUB_RUN_ID are required")
+	}
+	if attempt == "" {
+		attempt = "1"
+	}
+	url := fmt.Sprintf("%s/repos/%s/actions/runs/%s/attempts/%s/jobs?per_page=100", strings.TrimSuffix(api, "/"), repo, runID, attempt)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to list workflow jobs- %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to list workflow jobs- %s", resp.Status)
+	}
+	var body struct {
+		Jobs []githubJob `json:"jobs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to parse workflow jobs- %s", err.Error())
+	}
+
+	// The runner name identifies the job this post step runs in, matrix jobs share everything else
+	runner := getenv("RUNNER_NAME")
+	for _, job := range body.Jobs {
+		if job.Status != "in_progress" || (runner != "" && job.RunnerName != runner) {
+			continue
+		}
+		status := notifier.StatusSuccess
+		for _, step := range job.Steps {
+			switch step.Conclusion {
+			case "failure", "timed_out":
+				return notifier.StatusFailure, nil
+			case "cancelled":
+				status = notifier.StatusCancelled
+			}
+		}
+		return status, nil
+	}
+	return "", fmt.Errorf("no running job for runner %q in run %s", runner, runID)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joho/godotenv"
+)
+
+func TestHookArgs(t *testing.T) {
+	if got := hookArgs("/app/notifier-post", nil); len(got) != 1 || got[0] != "post" {
+		t.Errorf("hookArgs(notifier-post) = %v", got)
+	}
+	if got := hookArgs("/app/notification-handler", []string{"send"}); len(got) != 1 || got[0] != "send" {
+		t.Errorf("hookArgs(notification-handler) = %v", got)
+	}
+}
+
+func TestAutoFinalize(t *testing.T) {
+	jobs := `{"jobs":[
+		{"status":"completed","runner_name":"runner-2","steps":[{"name":"Test","conclusion":"failure"}]},
+		{"status":"in_progress","runner_name":"runner-1","steps":[
+			{"name":"Build","conclusion":"success"},
+			{"name":"Deploy","conclusion":"failure"},
+			{"name":"Post notify","conclusion":null}
+		]}
+	]}`
+	var authorization string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/org/repo/actions/runs/42/attempts/1/jobs" {
+			http.NotFound(w, r)
+			return
+		}
+		authorization = r.Header.Get("Authorization")
+		w.Write([]byte(jobs))
+	}))
+	defer api.Close()
+
+	fake := &fakeProvider{}
+	useFakeProvider(t, fake)
+	dir := t.TempDir()
+	stateFile := filepath.Join(dir, "state")
+	env := []string{
+		"INPUT_ACTION=send", "INPUT_CHANNEL=slack", "INPUT_MESSAGE=Deploy started", "INPUT_API_KEY=key",
+		"INPUT_CHANNEL_ID=#deploys", "INPUT_AUTO_FINALIZE=true", "INPUT_GITHUB_TOKEN=token",
+		"GITHUB_ACTIONS=true", "GITHUB_STATE=" + stateFile, "GITHUB_OUTPUT=" + filepath.Join(dir, "output"),
+		"GITHUB_API_URL=" + api.URL, "GITHUB_REPOSITORY=org/repo", "GITHUB_RUN_ID=42", "GITHUB_RUN_ATTEMPT=1",
+		"GITHUB_JOB=deploy", "RUNNER_NAME=runner-1",
+	}
+
+	if err := run(context.Background(), []string{"pre"}, env, &bytes.Buffer{}); err != nil {
+		t.Fatalf("pre error = %v", err)
+	}
+	state, err := godotenv.Read(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state["message_id"] != "1700000000.000100" || state["channel_id"] != "C123" {
+		t.Fatalf("GITHUB_STATE = %v", state)
+	}
+
+	// GitHub passes the saved state to the main and post steps as STATE_ variables
+	env = append(env, "STATE_message_id="+state["message_id"], "STATE_channel_id="+state["channel_id"])
+	if err := run(context.Background(), nil, env, &bytes.Buffer{}); err != nil {
+		t.Fatalf("main error = %v", err)
+	}
+	if err := run(context.Background(), []string{"post"}, env, &bytes.Buffer{}); err != nil {
+		t.Fatalf("post error = %v", err)
+	}
+
+	if strings.Join(fake.calls, ",") != "send,update:1700000000.000100" {
+		t.Errorf("provider calls = %v, main must not send again", fake.calls)
+	}
+	if fake.msgs[1] != "Job failed" {
+		t.Errorf("post message = %q, expected %q", fake.msgs[1], "Job failed")
+	}
+	if authorization != "Bearer token" {
+		t.Errorf("Authorization = %q", authorization)
+	}
+	outputs, err := os.ReadFile(filepath.Join(dir, "output"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(outputs), "message_id=1700000000.000100\n") {
+		t.Errorf("GITHUB_OUTPUT = %q, main must expose the message_id", outputs)
+	}
+}
+
+func TestHooksWithoutAutoFinalize(t *testing.T) {
+	fake := &fakeProvider{}
+	useFakeProvider(t, fake)
+	// Every use of the action runs the hooks, e.g. a verify step without channel_id
+	env := []string{"INPUT_ACTION=verify", "INPUT_CHANNEL=slack", "INPUT_API_KEY=key"}
+	for _, hook := range []string{"pre", "post"} {
+		if err := run(context.Background(), []string{hook}, env, &bytes.Buffer{}); err != nil {
+			t.Errorf("%s error = %v", hook, err)
+		}
+	}
+	if len(fake.calls) != 0 {
+		t.Errorf("provider calls = %v, expected none", fake.calls)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import "strings"
+
+// discoverJenkinsContext reads the Jenkins git plugin and multibranch variables
+func discoverJenkinsContext(in *ActionInputs, getenv func(string) string) {
+	setDefault(&in.CommitSha, getenv("GIT_COMMIT"))
+	// CHANGE_BRANCH is set for pull request builds in multibranch pipelines
+	setDefault(&in.Branch, getenv("CHANGE_BRANCH"))
+	setDefault(&in.Branch, getenv("BRANCH_NAME"))
+	setDefault(&in.Branch, strings.TrimPrefix(getenv("GIT_BRANCH"), "origin/"))
+	setDefault(&in.Author, getenv("CHANGE_AUTHOR"))
+	setDefault(&in.Author, getenv("GIT_AUTHOR_NAME"))
+	setDefault(&in.AuthorEmail, getenv("GIT_AUTHOR_EMAIL"))
+	setDefault(&in.WorkflowName, getenv("JOB_NAME"))
+	setDefault(&in.RunID, getenv("BUILD_NUMBER"))
+	setDefault(&in.RunURL, getenv("BUILD_URL"))
+	setDefault(&in.ReleaseTag, getenv("TAG_NAME"))
+	setDefault(&in.PRNumber, getenv("CHANGE_ID"))
+	setDefault(&in.PRTitle, getenv("CHANGE_TITLE"))
+	setDefault(&in.PRURL, getenv("CHANGE_URL"))
+	if remote := getenv("GIT_URL"); remote != "" {
+		setDefault(&in.CommitURL, commitURL(repoWebURL(remote), in.CommitSha))
+	}
+}
@@ -1,30 +1,63 @@
 package main
 
 import (
-	"cicd-notifier/pkg/slack"
-	"cicd-notifier/pkg/telegram"
+	"cicd-notifier/pkg/notifier"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
-var (
-	ParsedInputs ActionInputs
-)
+// defaultTimeout bounds all API calls of a run, including retries
+const defaultTimeout = 2 * time.Minute
 
-func init() {
-	initDev()
+// errInvalidConfig marks errors caused by the inputs rather than the notification itself
+var errInvalidConfig = errors.New("invalid configuration")
+
+// parseConfig builds the config from CLI args, INPUT_*/PLUGIN_*/NOTIFIER_* variables and the CI environment
+func parseConfig(args []string, env []string, output io.Writer) (ActionInputs, error) {
+	inputs := readInputs(env)
+	// CLI: "cicd-notifier <command> [flags]", flags override environment variables
+	if len(args) > 0 {
+		_, flags, err := parseArgs(args, output)
+		if err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return ActionInputs{}, err
+			}
+			return ActionInputs{}, fmt.Errorf("%w: %s", errInvalidConfig, err.Error())
+		}
+		for key, value := range flags {
+			inputs[key] = value
+		}
+	}
+	cfg := parseInputs(inputs, lookupEnv(env))
+	if err := validateInputs(cfg); err != nil {
+		return ActionInputs{}, err
+	}
+	return cfg, nil
+}
 
-	// Read inputs and initialize the struct
-	inputs := readInputs()
-	ParsedInputs = ActionInputs{
+// parseInputs maps the raw inputs to ActionInputs and fills missing commit info from the CI environment
+func parseInputs(inputs map[string]string, getenv func(string) string) ActionInputs {
+	parsed := ActionInputs{
 		Action:       strings.ToLower(inputs["action"]),
 		MsgID:        inputs["msg_id"],
+		Key:          inputs["key"],
+		StepName:     inputs["step_name"],
+		UpdateMode:   strings.ToLower(inputs["update_mode"]),
+		TopicID:      inputs["topic_id"],
+		Status:       inputs["status"],
+		StepURL:      inputs["step_url"],
 		Message:      inputs["message"],
 		ApiKey:       inputs["api_key"],
 		ChannelId:    inputs["channel_id"],
@@ -36,19 +69,124 @@ func init() {
 		CommitMsg:    inputs["commit_msg"],
 		WorkflowName: inputs["workflow_name"],
 		TimeZone:     inputs["timezone"],
+		RunURL:       inputs["run_url"],
+		CommitURL:    inputs["commit_url"],
+		CompareURL:   inputs["compare_url"],
+		DotenvFile:   inputs["dotenv_file"],
+		ReactAdd:     inputs["reactions"],
+		ReactRemove:  inputs["remove_reactions"],
+		StateFile:    inputs["state_file"],
+		GithubToken:  inputs["github_token"],
 	}
 
 	// Parse Channel
 	if channelStr := inputs["channel"]; channelStr != "" {
-		ParsedInputs.Channel = strings.TrimSpace(channelStr)
+		parsed.Channel = strings.TrimSpace(channelStr)
 	}
 
 	// Parse AddCommitInfo bool
 	if addCommitStr := inputs["add_commit_info"]; addCommitStr != "" {
-		if parsed, err := strconv.ParseBool(addCommitStr); err == nil {
-			ParsedInputs.AddCommitInfo = parsed
+		if b, err := strconv.ParseBool(addCommitStr); err == nil {
+			parsed.AddCommitInfo = b
+		}
+	}
+
+	// Parse SlackBlocks bool
+	if slackBlocksStr := inputs["slack_blocks"]; slackBlocksStr != "" {
+		if b, err := strconv.ParseBool(slackBlocksStr); err == nil {
+			parsed.SlackBlocks = b
+		}
+	}
+
+	// Parse retry policy, invalid values keep the defaults
+	parsed.Retries = notifier.DefaultRetryPolicy.MaxAttempts
+	if retriesStr := inputs["retries"]; retriesStr != "" {
+		if n, err := strconv.Atoi(retriesStr); err == nil {
+			parsed.Retries = n
+		}
+	}
+	parsed.RetryDelay = notifier.DefaultRetryPolicy.BaseDelay
+	if delayStr := inputs["retry_delay"]; delayStr != "" {
+		if d, err := time.ParseDuration(delayStr); err == nil {
+			parsed.RetryDelay = d
+		}
+	}
+
+	// Parse FailOnError bool, notifications are fatal unless disabled
+	parsed.FailOnError = true
+	if failStr := inputs["fail_on_error"]; failStr != "" {
+		if b, err := strconv.ParseBool(failStr); err == nil {
+			parsed.FailOnError = b
+		}
+	}
+
+	// Parse ReplyBroadcast bool
+	if broadcastStr := inputs["reply_broadcast"]; broadcastStr != "" {
+		if b, err := strconv.ParseBool(broadcastStr); err == nil {
+			parsed.ReplyBroadcast = b
+		}
+	}
+
+	// Parse UnpinPrevious bool, pin replaces the previous deploy unless disabled
+	parsed.UnpinPrevious = true
+	if unpinStr := inputs["unpin_previous"]; unpinStr != "" {
+		if b, err := strconv.ParseBool(unpinStr); err == nil {
+			parsed.UnpinPrevious = b
+		}
+	}
+
+	// Parse DryRun bool
+	if dryRunStr := inputs["dry_run"]; dryRunStr != "" {
+		if b, err := strconv.ParseBool(dryRunStr); err == nil {
+			parsed.DryRun = b
+		}
+	}
+
+	// Parse AutoFinalize bool
+	if autoStr := inputs["auto_finalize"]; autoStr != "" {
+		if b, err := strconv.ParseBool(autoStr); err == nil {
+			parsed.AutoFinalize = b
+		}
+	}
+
+	// Parse timeout, invalid values keep the default
+	parsed.Timeout = defaultTimeout
+	if timeoutStr := inputs["timeout"]; timeoutStr != "" {
+		if d, err := time.ParseDuration(timeoutStr); err == nil {
+			parsed.Timeout = d
+		}
+	}
+
+	// Parse leg duration, invalid values are ignored and the duration is shown as unknown
+	if durationStr := inputs["duration"]; durationStr != "" {
+		if d, err := time.ParseDuration(durationStr); err == nil {
+			parsed.Duration = d
 		}
 	}
+
+	// Parse the expected number of matrix legs, invalid values are ignored and the legs are counted as they report
+	if totalStr := inputs["matrix_total"]; totalStr != "" {
+		if n, err := strconv.Atoi(totalStr); err == nil && n > 0 {
+			parsed.MatrixTotal = n
+		}
+	}
+
+	// Parse cleanup age, invalid values are rejected by validateInputs
+	if olderStr := inputs["older_than"]; olderStr != "" {
+		if d, err := time.ParseDuration(olderStr); err == nil {
+			parsed.OlderThan = d
+		}
+	}
+
+	// Fill missing commit info from the CI environment
+	ci := detectCIEnvironment(getenv)
+	ci.discover(&parsed, getenv)
+	parsed.CISystem = ci.title
+	discoverHookState(&parsed, getenv)
+	if parsed.Action == "aggregate" {
+		discoverMatrix(&parsed)
+	}
+	return parsed
 }
 func initDev() {
 	err := godotenv.Load()
@@ -56,80 +194,122 @@ func initDev() {
 		slog.Info(".env file doesn't exist")
 	}
 }
-func main() {
-	// Validate inputs
-	validateInputs()
-	tz, err := time.LoadLocation(ParsedInputs.TimeZone)
+
+// run executes one notifier command, env uses the os.Environ format and outputs go to stdout or the CI sink
+func run(ctx context.Context, args []string, env []string, stdout io.Writer) error {
+	cfg, err := parseConfig(args, env, stdout)
 	if err != nil {
-		slog.Info("Failed to load timezone: %v", slog.String("error", err.Error()))
+		return err
+	}
+	getenv := lookupEnv(env)
+	tz, err := time.LoadLocation(cfg.TimeZone)
+	if err != nil {
+		slog.Info("Failed to load timezone", slog.String("error", err.Error()))
 		tz = time.UTC
 	}
-	var msg string
-	if ParsedInputs.Action == "send" {
-		if ParsedInputs.AddCommitInfo {
-			msg = templateCommitInfo()
-		}
-		msg += fmt.Sprintf("* - %s:* %s \n", ParsedInputs.Message, time.Now().In(tz).Format(time.DateTime))
-		switch strings.ToLower(ParsedInputs.Channel) {
-		case "slack":
-			c, err := slack.InitClient(ParsedInputs.ApiKey)
-			if err != nil {
-				slog.Error("Failed to initialize slack client", slog.String("error", err.Error()))
-				os.Exit(1)
-			}
-			chId, msgId, err := c.Send(ParsedInputs.ChannelId, msg)
-			if err != nil {
-				slog.Error("Failed To Post Slack message", slog.String("error", err.Error()))
-				os.Exit(1)
-			}
-			addOutput("message_id", msgId)
-			addOutput("channel_id", chId)
-		case "telegram":
-			c, err := telegram.InitClient(ParsedInputs.ApiKey)
-			if err != nil {
-				slog.Error("Failed to initialize telegram client", slog.String("error", err.Error()))
-				os.Exit(1)
-			}
-			msgId, err := c.Send(ParsedInputs.ChannelId, msg)
-			if err != nil {
-				slog.Error("Failed To Post Telegram message", slog.String("error", err.Error()))
-				os.Exit(1)
-			}
-			addOutput("message_id", msgId)
-		}
+
+	if cfg.Action == "preview" {
+		msg := message(cfg)
+		msg.Time = time.Now().In(tz)
+		return previewMessage(cfg, msg, stdout)
 	}
-	if ParsedInputs.Action == "update" {
-		switch strings.ToLower(ParsedInputs.Channel) {
-		case "slack":
-			c, err := slack.InitClient(ParsedInputs.ApiKey)
-			if err != nil {
-				slog.Error("Failed to initialize slack client", slog.String("error", err.Error()))
-				os.Exit(1)
-			}
-			msg, err = c.GetMsgContent(ParsedInputs.ChannelId, ParsedInputs.MsgID)
-			if err != nil {
-				slog.Error("Failed get slack message Content", slog.String("error", err.Error()))
-				os.Exit(1)
-			}
-			msg += fmt.Sprintf("- *%s:* %s \n", ParsedInputs.Message, time.Now().In(tz).Format(time.DateTime))
-			err = c.Delete(ParsedInputs.ChannelId, ParsedInputs.MsgID)
-			if err != nil {
-				slog.Error("Failed to delete slack message", slog.String("error", err.Error()))
-				os.Exit(1)
-			}
-			chId, msgId, err := c.Send(ParsedInputs.ChannelId, msg)
-			if err != nil {
-				slog.Error("Failed To send Slack message", slog.String("error", err.Error()))
-				os.Exit(1)
-			}
-			addOutput("message_id", msgId)
-			addOutput("channel_id", chId)
-		case "telegram":
-			slog.Error("Update message is not supported in Telegram")
-			os.Exit(1)
+	switch {
+	case isHook(cfg.Action) && !cfg.AutoFinalize:
+		return nil
+	case cfg.Action == "post" && cfg.MsgID == "":
+		slog.Warn("No message_id in GITHUB_STATE, the started message wasn't sent")
+		return nil
+	case cfg.AutoFinalize && !isHook(cfg.Action):
+		// pre already sent the message, post finalizes it, expose its IDs to later steps
+		outputs := map[string]string{"message_id": cfg.MsgID, "channel_id": cfg.ChannelId}
+		return setOutputs(cfg, outputs, getenv, stdout)
+	}
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+	outputs := make(map[string]string)
+	if err := notify(ctx, cfg, tz, getenv, outputs, stdout); err != nil {
+		// Config errors and cancelled jobs stay fatal, only the notification itself may fail softly
+		if cfg.FailOnError || errors.Is(err, errInvalidConfig) || errors.Is(err, context.Canceled) {
+			return err
 		}
+		// The deploy itself succeeded, report the failure without failing the step
+		detectCIEnvironment(getenv).warning(stdout, "Notification failed: "+err.Error())
+		outputs["error"] = strings.ReplaceAll(err.Error(), "\n", " ")
 	}
 	// Set outputs for GitHub Actions
-	setOutputs()
-	os.Exit(0)
+	return setOutputs(cfg, outputs, getenv, stdout)
+}
+
+// notify runs the provider command selected by cfg.Action
+func notify(ctx context.Context, cfg ActionInputs, tz *time.Location, getenv func(string) string, outputs map[string]string, stdout io.Writer) error {
+	var p notifier.Provider
+	if cfg.DryRun {
+		p = newDryRunProvider(cfg, stdout)
+	} else {
+		var err error
+		if p, err = newProvider(ctx, cfg); err != nil {
+			return err
+		}
+	}
+	n := notifier.New(p, cfg.ChannelId, notifier.WithLocation(tz))
+	switch cfg.Action {
+	case "send":
+		return sendMessage(ctx, n, cfg, outputs)
+	case "update":
+		return updateMessage(ctx, n, cfg, outputs)
+	case "upsert", "aggregate":
+		return upsertMessage(ctx, n, cfg, outputs)
+	case "reply":
+		return replyMessage(ctx, n, cfg, outputs)
+	case "react":
+		return reactToMessage(ctx, n, cfg, outputs)
+	case "pin":
+		return pinMessage(ctx, n, cfg, outputs)
+	case "unpin":
+		return unpinMessage(ctx, n, cfg)
+	case "set_topic":
+		return setTopic(ctx, n, cfg)
+	case "delete":
+		return deleteMessage(ctx, n, cfg)
+	case "cleanup":
+		return cleanupMessages(ctx, n, cfg, outputs)
+	case "verify":
+		return verifyCredentials(ctx, n, stdout)
+	case "pre":
+		return startJob(ctx, n, cfg, getenv)
+	case "post":
+		return finishJob(ctx, n, cfg, getenv, outputs)
+	}
+	return nil
+}
+
+// exitCode maps the error returned by run to the process exit code
+func exitCode(err error) int {
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errInvalidConfig):
+		return exitUsage
+	case errors.Is(err, context.DeadlineExceeded):
+		return exitTimeout
+	case errors.Is(err, context.Canceled):
+		return exitSignal
+	default:
+		return exitFailure
+	}
+}
+
+func main() {
+	initDev()
+	// Job cancellation sends SIGTERM (SIGINT locally), in-flight requests are cancelled
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := run(ctx, hookArgs(os.Args[0], os.Args[1:]), os.Environ(), os.Stdout)
+	stop()
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		slog.Error("cicd-notifier failed", slog.String("error", err.Error()))
+	}
+	os.Exit(exitCode(err))
 }
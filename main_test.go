@@ -0,0 +1,487 @@
+package main
+
+import (
+	"bytes"
+	"cicd-notifier/pkg/notifier"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeProvider records calls instead of talking to Slack/Telegram
+type fakeProvider struct {
+	calls    []string
+	msgs     []string
+	err      error
+	existing string // message Find returns
+}
+
+func (f *fakeProvider) Send(_ context.Context, channelID string, msg notifier.Message) (notifier.Ref, error) {
+	f.calls = append(f.calls, "send")
+	f.msgs = append(f.msgs, notifier.FormatText(msg))
+	return notifier.Ref{ChannelID: "C123", MessageID: "1700000000.000100"}, f.err
+}
+
+func (f *fakeProvider) Update(_ context.Context, ref notifier.Ref, msg notifier.Message) (notifier.Ref, error) {
+	f.calls = append(f.calls, "update:"+ref.MessageID)
+	f.msgs = append(f.msgs, msg.Text)
+	return notifier.Ref{ChannelID: "C123", MessageID: "1700000000.000200"}, f.err
+}
+
+func (f *fakeProvider) Find(_ context.Context, channelID, key string) (notifier.Ref, bool, error) {
+	f.calls = append(f.calls, "find:"+key)
+	return notifier.Ref{ChannelID: "C123", MessageID: f.existing}, f.existing != "", f.err
+}
+
+func (f *fakeProvider) Reply(_ context.Context, ref notifier.Ref, msg notifier.Message) (notifier.Ref, error) {
+	f.calls = append(f.calls, "reply:"+ref.MessageID)
+	f.msgs = append(f.msgs, msg.Text)
+	return notifier.Ref{ChannelID: "C123", MessageID: "1700000000.000300"}, f.err
+}
+
+func (f *fakeProvider) React(_ context.Context, ref notifier.Ref, add, remove []string) error {
+	f.calls = append(f.calls, fmt.Sprintf("react:%s+%v-%v", ref.MessageID, add, remove))
+	return f.err
+}
+
+func (f *fakeProvider) Pin(_ context.Context, ref notifier.Ref, exclusive bool) error {
+	f.calls = append(f.calls, fmt.Sprintf("pin:%s:%t", ref.MessageID, exclusive))
+	return f.err
+}
+
+func (f *fakeProvider) Unpin(_ context.Context, ref notifier.Ref) error {
+	f.calls = append(f.calls, "unpin:"+ref.MessageID)
+	return f.err
+}
+
+func (f *fakeProvider) SetTopic(_ context.Context, channelID, topic string) error {
+	f.calls = append(f.calls, "topic")
+	f.msgs = append(f.msgs, topic)
+	return f.err
+}
+
+func (f *fakeProvider) Delete(_ context.Context, ref notifier.Ref) error {
+	f.calls = append(f.calls, "delete:"+ref.MessageID)
+	return f.err
+}
+
+func (f *fakeProvider) Cleanup(_ context.Context, channelID string, before time.Time) (int, error) {
+	f.calls = append(f.calls, "cleanup")
+	return 3, f.err
+}
+
+func (f *fakeProvider) Verify(context.Context, string) (string, error) {
+	f.calls = append(f.calls, "verify")
+	return "fake credentials OK", f.err
+}
+
+// useFakeProvider replaces newProvider for the duration of the test
+func useFakeProvider(t *testing.T, fake *fakeProvider) {
+	t.Helper()
+	original := newProvider
+	newProvider = func(context.Context, ActionInputs) (notifier.Provider, error) { return fake, nil }
+	t.Cleanup(func() { newProvider = original })
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name            string
+		args            []string
+		env             []string
+		providerErr     error
+		existing        string
+		expectedCalls   []string
+		expectedMsg     string
+		expectedOutputs string
+		expectedStdout  string
+		expectedExit    int
+	}{
+		{
+			name: "send as GitHub Action",
+			env: []string{
+				"INPUT_ACTION=send", "INPUT_CHANNEL=slack", "INPUT_MESSAGE=Deploy started",
+				"INPUT_API_KEY=key", "INPUT_CHANNEL_ID=#deploys", "INPUT_TIMEZONE=UTC",
+			},
+			expectedCalls:   []string{"send"},
+			expectedMsg:     "* - Deploy started:*",
+			expectedOutputs: "channel_id=C123\nmessage_id=1700000000.000100\n",
+		},
+		{
+			name: "send with commit info",
+			env: []string{
+				"INPUT_ACTION=send", "INPUT_CHANNEL=slack", "INPUT_MESSAGE=Deploy started",
+				"INPUT_API_KEY=key", "INPUT_CHANNEL_ID=#deploys", "INPUT_ADD_COMMIT_INFO=true",
+				"INPUT_COMMIT_SHA=abc123",
+			},
+			expectedCalls:   []string{"send"},
+			expectedMsg:     "📦 *Github Workflow*\n\n📌 *Commit:* `abc123`\n\n* - Deploy started:*",
+			expectedOutputs: "channel_id=C123\nmessage_id=1700000000.000100\n",
+		},
+		{
+			name: "update as GitHub Action",
+			env: []string{
+				"INPUT_ACTION=update", "INPUT_CHANNEL=slack", "INPUT_MESSAGE=Deployed",
+				"INPUT_API_KEY=key", "INPUT_CHANNEL_ID=C123", "INPUT_MSG_ID=1700000000.000100",
+			},
+			expectedCalls:   []string{"update:1700000000.000100"},
+			expectedMsg:     "Deployed",
+			expectedOutputs: "channel_id=C123\nmessage_id=1700000000.000200\n",
+		},
+		{
+			name: "commit info names the detected CI system",
+			env: []string{
+				"INPUT_ACTION=send", "INPUT_CHANNEL=slack", "INPUT_MESSAGE=Deploy started",
+				"INPUT_API_KEY=key", "INPUT_CHANNEL_ID=#deploys", "INPUT_ADD_COMMIT_INFO=true",
+				"JENKINS_URL=https://jenkins.example.com/", "GIT_COMMIT=abc123",
+			},
+			expectedCalls: []string{"send"},
+			expectedMsg:   "📦 *Jenkins Build*\n\n📌 *Commit:* `abc123`\n",
+		},
+		{
+			name: "auto_finalize with telegram without state_file is a usage error",
+			env: []string{
+				"INPUT_ACTION=send", "INPUT_CHANNEL=telegram", "INPUT_MESSAGE=Deploy started",
+				"INPUT_API_KEY=key", "INPUT_CHANNEL_ID=-100", "INPUT_AUTO_FINALIZE=true",
+			},
+			expectedExit: exitUsage,
+		},
+		{
+			name:         "telegram update without state_file is a usage error",
+			args:         []string{"update", "--channel=telegram", "--api_key=key", "--channel_id=-100", "--msg_id=5", "--message=Done"},
+			expectedExit: exitUsage,
+		},
+		{
+			name:          "telegram update in the thread needs no state_file",
+			args:          []string{"update", "--channel=telegram", "--api_key=key", "--channel_id=-100", "--msg_id=5", "--message=Done", "--update_mode=thread"},
+			expectedCalls: []string{"reply:5"},
+		},
+		{
+			name:            "upsert sends the first message",
+			env:             []string{"INPUT_ACTION=upsert", "INPUT_CHANNEL=slack", "INPUT_MESSAGE=Build", "INPUT_API_KEY=key", "INPUT_CHANNEL_ID=C123", "INPUT_KEY=repo-42"},
+			expectedCalls:   []string{"find:repo-42", "send", "find:repo-42"},
+			expectedOutputs: "channel_id=C123\ncreated=true\nmessage_id=1700000000.000100\n",
+		},
+		{
+			name:            "upsert updates the existing message",
+			env:             []string{"INPUT_ACTION=upsert", "INPUT_CHANNEL=slack", "INPUT_MESSAGE=Deploy", "INPUT_API_KEY=key", "INPUT_CHANNEL_ID=C123", "INPUT_KEY=repo-42"},
+			existing:        "1700000000.000100",
+			expectedCalls:   []string{"find:repo-42", "update:1700000000.000100"},
+			expectedOutputs: "channel_id=C123\ncreated=false\nmessage_id=1700000000.000200\n",
+		},
+		{
+			name:         "upsert without key is a usage error",
+			args:         []string{"upsert", "--channel=slack", "--api_key=key", "--channel_id=C1", "--message=x"},
+			expectedExit: exitUsage,
+		},
+		{
+			name: "aggregate reports a matrix leg into the run message",
+			env: []string{
+				"INPUT_ACTION=aggregate", "INPUT_CHANNEL=slack", "INPUT_API_KEY=key", "INPUT_CHANNEL_ID=C123",
+				"INPUT_STEP_NAME=linux", "INPUT_STATUS=success", "INPUT_DURATION=3m12s",
+				"GITHUB_REPOSITORY=org/repo", "GITHUB_RUN_ID=42",
+			},
+			existing:        "1700000000.000100",
+			expectedCalls:   []string{"find:matrix/org/repo/42", "update:1700000000.000100"},
+			expectedOutputs: "channel_id=C123\ncreated=false\nmessage_id=1700000000.000200\n",
+		},
+		{
+			name:         "aggregate without status is a usage error",
+			args:         []string{"aggregate", "--channel=slack", "--api_key=key", "--channel_id=C1", "--key=k", "--step_name=linux"},
+			expectedExit: exitUsage,
+		},
+		{
+			name:            "reply keeps the parent message id",
+			env:             []string{"INPUT_ACTION=reply", "INPUT_CHANNEL=slack", "INPUT_MESSAGE=Tests failed", "INPUT_API_KEY=key", "INPUT_CHANNEL_ID=C123", "INPUT_MSG_ID=1700000000.000100"},
+			expectedCalls:   []string{"reply:1700000000.000100"},
+			expectedMsg:     "Tests failed",
+			expectedOutputs: "channel_id=C123\nmessage_id=1700000000.000100\nreply_id=1700000000.000300\n",
+		},
+		{
+			name:          "update in thread mode replies",
+			args:          []string{"update", "--channel=slack", "--api_key=key", "--channel_id=C1", "--msg_id=1.1", "--message=Done", "--update_mode=thread"},
+			expectedCalls: []string{"reply:1.1"},
+		},
+		{
+			name:         "unknown update mode is a usage error",
+			args:         []string{"update", "--channel=slack", "--api_key=key", "--channel_id=C1", "--msg_id=1.1", "--message=Done", "--update_mode=append"},
+			expectedExit: exitUsage,
+		},
+		{
+			name:          "react swaps reactions",
+			args:          []string{"react", "--channel=slack", "--api_key=key", "--channel_id=C1", "--msg_id=1.1", "--reactions=white_check_mark", "--remove_reactions=hourglass, :eyes:"},
+			expectedCalls: []string{"react:1.1+[white_check_mark]-[hourglass :eyes:]"},
+		},
+		{
+			name:         "react without reactions is a usage error",
+			args:         []string{"react", "--channel=slack", "--api_key=key", "--channel_id=C1", "--msg_id=1.1"},
+			expectedExit: exitUsage,
+		},
+		{
+			name:          "pin unpins the previous deploy",
+			args:          []string{"pin", "--channel=slack", "--api_key=key", "--channel_id=C1", "--msg_id=1.1"},
+			expectedCalls: []string{"pin:1.1:true"},
+		},
+		{
+			name:          "pin keeping the previous pins",
+			args:          []string{"pin", "--channel=slack", "--api_key=key", "--channel_id=C1", "--msg_id=1.1", "--unpin_previous=false"},
+			expectedCalls: []string{"pin:1.1:false"},
+		},
+		{
+			name:          "set_topic shows the deployed version",
+			args:          []string{"set_topic", "--channel=telegram", "--api_key=key", "--channel_id=42", "--image_tag=v1.2.0", "--commit_sha=abc1234567", "--branch=main"},
+			expectedCalls: []string{"topic"},
+			expectedMsg:   "🚀 Live: v1.2.0 · abc1234 · main",
+		},
+		{
+			name:           "delete from CLI",
+			args:           []string{"delete", "--channel=telegram", "--api_key=key", "--channel_id=42", "--msg_id=7"},
+			expectedCalls:  []string{"delete:7"},
+			expectedStdout: "Outputs:\n",
+		},
+		{
+			name:            "cleanup outputs the deleted count",
+			env:             []string{"INPUT_ACTION=cleanup", "INPUT_CHANNEL=slack", "INPUT_API_KEY=key", "INPUT_CHANNEL_ID=C1", "INPUT_OLDER_THAN=24h"},
+			expectedCalls:   []string{"cleanup"},
+			expectedOutputs: "deleted=3\n",
+		},
+		{
+			name:         "cleanup without older_than is a usage error",
+			args:         []string{"cleanup", "--channel=slack", "--api_key=key", "--channel_id=C1"},
+			expectedExit: exitUsage,
+		},
+		{
+			name:           "verify from CLI",
+			args:           []string{"verify", "--channel=slack", "--api_key=key"},
+			expectedCalls:  []string{"verify"},
+			expectedStdout: "fake credentials OK\nOutputs:\n",
+		},
+		{
+			name:           "preview does not call the provider",
+			args:           []string{"preview", "--channel=telegram", "--message=Hello"},
+			expectedStdout: "* - Hello:*",
+		},
+		{
+			name:           "preview renders slack blocks",
+			args:           []string{"preview", "--channel=slack", "--slack_blocks=true", "--message=Hello"},
+			expectedStdout: "chat.postMessage {\n  \"channel\": \"\",\n  \"text\": \"* - Hello:*",
+		},
+		{
+			name:           "dry run send does not call the provider",
+			args:           []string{"send", "--channel=telegram", "--api_key=key", "--channel_id=42", "--message=Hello", "--dry_run=true"},
+			expectedStdout: "sendMessage {\n  \"chat_id\": \"42\",\n  \"text\": \"* - Hello:*",
+		},
+		{
+			name:           "dry run send posts in the topic",
+			args:           []string{"send", "--channel=telegram", "--api_key=key", "--channel_id=42", "--topic_id=7", "--message=Hello", "--dry_run=true"},
+			expectedStdout: "\"message_thread_id\": \"7\"",
+		},
+		{
+			name:           "dry run update prints the appended line",
+			args:           []string{"update", "--channel=slack", "--api_key=key", "--channel_id=C1", "--msg_id=1.1", "--message=Done", "--dry_run=true"},
+			expectedStdout: "chat.update (appended) {\n  \"channel\": \"C1\",\n  \"text\": \"- *Done:*",
+		},
+		{
+			name:         "missing message is a usage error",
+			args:         []string{"send", "--channel=slack", "--api_key=key", "--channel_id=C1"},
+			expectedExit: exitUsage,
+		},
+		{
+			name:         "update without msg_id is a usage error",
+			env:          []string{"INPUT_ACTION=update", "INPUT_CHANNEL=slack", "INPUT_MESSAGE=x", "INPUT_API_KEY=key", "INPUT_CHANNEL_ID=C1"},
+			expectedExit: exitUsage,
+		},
+		{
+			name:          "step update without message",
+			args:          []string{"update", "--channel=slack", "--api_key=key", "--channel_id=C1", "--msg_id=1.1", "--step_name=build", "--status=success"},
+			expectedCalls: []string{"update:1.1"},
+		},
+		{
+			name:         "unknown status is a usage error",
+			args:         []string{"send", "--channel=slack", "--api_key=key", "--channel_id=C1", "--message=x", "--status=done"},
+			expectedExit: exitUsage,
+		},
+		{
+			name:          "provider failure",
+			args:          []string{"send", "--channel=slack", "--api_key=key", "--channel_id=C1", "--message=x"},
+			providerErr:   errors.New("channel_not_found"),
+			expectedCalls: []string{"send"},
+			expectedExit:  exitFailure,
+		},
+		{
+			name: "non-fatal provider failure",
+			env: []string{
+				"INPUT_ACTION=send", "INPUT_CHANNEL=slack", "INPUT_MESSAGE=x",
+				"INPUT_API_KEY=key", "INPUT_CHANNEL_ID=C1", "INPUT_FAIL_ON_ERROR=false",
+			},
+			providerErr:     errors.New("channel_not_found"),
+			expectedCalls:   []string{"send"},
+			expectedStdout:  "::warning::Notification failed: failed to post slack message- channel_not_found\n",
+			expectedOutputs: "error=failed to post slack message- channel_not_found\n",
+		},
+		{
+			name:         "config errors stay fatal with fail_on_error false",
+			args:         []string{"send", "--channel=slack", "--api_key=key", "--channel_id=C1", "--fail_on_error=false"},
+			expectedExit: exitUsage,
+		},
+		{
+			name:          "timeout expired",
+			args:          []string{"send", "--channel=slack", "--api_key=key", "--channel_id=C1", "--message=x", "--timeout=1s"},
+			providerErr:   fmt.Errorf("failed to Post Slack Message- %w", context.DeadlineExceeded),
+			expectedCalls: []string{"send"},
+			expectedExit:  exitTimeout,
+		},
+		{
+			name:          "cancelled by signal",
+			args:          []string{"send", "--channel=slack", "--api_key=key", "--channel_id=C1", "--message=x"},
+			providerErr:   fmt.Errorf("failed to Post Slack Message- %w", context.Canceled),
+			expectedCalls: []string{"send"},
+			expectedExit:  exitSignal,
+		},
+		{
+			name:         "help",
+			args:         []string{"--help"},
+			expectedExit: exitOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeProvider{err: tt.providerErr, existing: tt.existing}
+			useFakeProvider(t, fake)
+
+			env := tt.env
+			outputFile := filepath.Join(t.TempDir(), "github_output")
+			if tt.expectedOutputs != "" {
+				env = append(env, "GITHUB_ACTIONS=true", "GITHUB_OUTPUT="+outputFile)
+			}
+			var stdout bytes.Buffer
+			err := run(context.Background(), tt.args, env, &stdout)
+
+			if code := exitCode(err); code != tt.expectedExit {
+				t.Fatalf("run() error = %v, exit code %d, expected %d", err, code, tt.expectedExit)
+			}
+			if strings.Join(fake.calls, ",") != strings.Join(tt.expectedCalls, ",") {
+				t.Errorf("provider calls = %v, expected %v", fake.calls, tt.expectedCalls)
+			}
+			if tt.expectedMsg != "" && (len(fake.msgs) == 0 || !strings.HasPrefix(fake.msgs[0], tt.expectedMsg)) {
+				t.Errorf("message = %q, expected prefix %q", fake.msgs, tt.expectedMsg)
+			}
+			if tt.expectedStdout != "" && !strings.Contains(stdout.String(), tt.expectedStdout) {
+				t.Errorf("stdout = %q, expected to contain %q", stdout.String(), tt.expectedStdout)
+			}
+			if tt.expectedOutputs != "" {
+				data, err := os.ReadFile(outputFile)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != tt.expectedOutputs {
+					t.Errorf("GITHUB_OUTPUT = %q, expected %q", string(data), tt.expectedOutputs)
+				}
+			}
+		})
+	}
+}
+
+// channelProvider keeps the messages of one channel in memory, shared by concurrent jobs
+type channelProvider struct {
+	mu       sync.Mutex
+	sent     int
+	messages map[string]*notifier.Timeline
+}
+
+func (c *channelProvider) Send(_ context.Context, channelID string, msg notifier.Message) (notifier.Ref, error) {
+	// Posting takes a while, other jobs find no message in the meantime
+	time.Sleep(time.Millisecond)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent++
+	id := fmt.Sprintf("%04d", c.sent)
+	c.messages[id] = notifier.NewTimeline(msg)
+	return notifier.Ref{ChannelID: channelID, MessageID: id}, nil
+}
+
+func (c *channelProvider) Update(_ context.Context, ref notifier.Ref, msg notifier.Message) (notifier.Ref, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.messages[ref.MessageID]
+	if !ok {
+		return notifier.Ref{}, fmt.Errorf("message %s not found", ref.MessageID)
+	}
+	t.Add(msg)
+	return ref, nil
+}
+
+func (c *channelProvider) Find(_ context.Context, channelID, key string) (notifier.Ref, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	oldest := ""
+	for id, t := range c.messages {
+		if t.Key == key && (oldest == "" || id < oldest) {
+			oldest = id
+		}
+	}
+	return notifier.Ref{ChannelID: channelID, MessageID: oldest}, oldest != "", nil
+}
+
+func (c *channelProvider) Delete(_ context.Context, ref notifier.Ref) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.messages, ref.MessageID)
+	return nil
+}
+
+func (c *channelProvider) Verify(context.Context, string) (string, error) {
+	return "", nil
+}
+
+func TestConcurrentUpsertsConverge(t *testing.T) {
+	for _, action := range []string{"upsert", "aggregate"} {
+		t.Run(action, func(t *testing.T) {
+			provider := &channelProvider{messages: map[string]*notifier.Timeline{}}
+			n := notifier.New(provider, "C1")
+
+			const jobs = 10
+			var wg sync.WaitGroup
+			ids := make([]string, jobs)
+			for i := range jobs {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					cfg := ActionInputs{Action: action, Channel: "slack", Key: "repo-42", Message: fmt.Sprintf("job-%d done", i),
+						StepName: fmt.Sprintf("job-%d", i), Status: "success", MatrixTotal: jobs}
+					outputs := map[string]string{}
+					if err := upsertMessage(context.Background(), n, cfg, outputs); err != nil {
+						t.Errorf("upsertMessage() error = %v", err)
+					}
+					ids[i] = outputs["message_id"]
+				}()
+			}
+			wg.Wait()
+
+			if len(provider.messages) != 1 {
+				t.Fatalf("channel has %d messages, expected the duplicates deleted", len(provider.messages))
+			}
+			timeline, ok := provider.messages["0001"]
+			if !ok {
+				t.Fatalf("channel kept %v, expected the oldest message 0001", provider.messages)
+			}
+			if len(timeline.Steps) != jobs {
+				t.Errorf("message has %d steps, expected one per job", len(timeline.Steps))
+			}
+			if action == "aggregate" && timeline.Overall() != notifier.StatusSuccess {
+				t.Errorf("Overall() = %q after every leg reported", timeline.Overall())
+			}
+			for i, id := range ids {
+				if id != "0001" {
+					t.Errorf("job %d reported message_id %q, expected 0001", i, id)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// outputSink persists step outputs so later steps/jobs can reuse them (e.g. message_id for update)
+type outputSink interface {
+	Write(outputs map[string]string) error
+}
+
+// fileSink appends KEY=value lines to a file, GITHUB_OUTPUT or a dotenv report
+type fileSink struct {
+	path      string
+	upperKeys bool
+}
+
+func (s fileSink) Write(outputs map[string]string) error {
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open output file- %s", err.Error())
+	}
+	defer file.Close()
+
+	for _, key := range sortedKeys(outputs) {
+		name := key
+		if s.upperKeys {
+			name = strings.ToUpper(key)
+		}
+		if _, err := fmt.Fprintf(file, "%s=%s\n", name, outputs[key]); err != nil {
+			return fmt.Errorf("failed to write output file- %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// stdoutSink prints outputs to the console when there is nowhere to persist them
+type stdoutSink struct {
+	w io.Writer
+}
+
+func (s stdoutSink) Write(outputs map[string]string) error {
+	fmt.Fprintln(s.w, "Outputs:")
+	for _, key := range sortedKeys(outputs) {
+		fmt.Fprintf(s.w, "%s=%s\n", key, outputs[key])
+	}
+	return nil
+}
+
+// buildkiteMetadataSink stores outputs as build meta-data, later steps read them with meta-data get
+type buildkiteMetadataSink struct{}
+
+func (buildkiteMetadataSink) Write(outputs map[string]string) error {
+	for _, key := range sortedKeys(outputs) {
+		if _, err := buildkiteAgent("meta-data", "set", key, outputs[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func githubSink(_ ActionInputs, getenv func(string) string, stdout io.Writer) outputSink {
+	if path := getenv("GITHUB_OUTPUT"); path != "" {
+		return fileSink{path: path}
+	}
+	return stdoutSink{w: stdout}
+}
+
+// dotenvReportSink writes upper-case keys (MESSAGE_ID) for artifacts:reports:dotenv
+func dotenvReportSink(in ActionInputs, _ func(string) string, _ io.Writer) outputSink {
+	path := in.DotenvFile
+	if path == "" {
+		path = "notifier.env"
+	}
+	return fileSink{path: path, upperKeys: true}
+}
+
+func buildkiteSink(ActionInputs, func(string) string, io.Writer) outputSink {
+	return buildkiteMetadataSink{}
+}
+
+func consoleSink(_ ActionInputs, _ func(string) string, stdout io.Writer) outputSink {
+	return stdoutSink{w: stdout}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
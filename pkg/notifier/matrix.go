@@ -0,0 +1,102 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// statusOrder is the order leg counts are listed in, problems first
+var statusOrder = []Status{StatusFailure, StatusCancelled, StatusRunning, StatusPending, StatusSuccess, StatusSkipped}
+
+// Legs returns the matrix legs, the named steps of the timeline
+func (t *Timeline) Legs() []Step {
+	var legs []Step
+	for _, step := range t.Steps {
+		if step.Name != "" {
+			legs = append(legs, step)
+		}
+	}
+	return legs
+}
+
+// Overall derives the matrix status from its legs: failure when any leg failed, running while
+// a leg hasn't finished or fewer than MatrixTotal legs reported, cancelled when any leg was
+// cancelled, skipped when all were, else success
+func (t *Timeline) Overall() Status {
+	legs := t.Legs()
+	counts := statusCounts(legs)
+	switch {
+	case len(legs) == 0:
+		return ""
+	case counts[StatusFailure] > 0:
+		return StatusFailure
+	case counts[StatusRunning] > 0 || counts[StatusPending] > 0 || counts[""] > 0 || len(legs) < t.MatrixTotal:
+		return StatusRunning
+	case counts[StatusCancelled] > 0:
+		return StatusCancelled
+	case counts[StatusSkipped] == len(legs):
+		return StatusSkipped
+	}
+	return StatusSuccess
+}
+
+func statusCounts(legs []Step) map[Status]int {
+	counts := make(map[Status]int)
+	for _, leg := range legs {
+		counts[leg.Status]++
+	}
+	return counts
+}
+
+// FormatMatrixStatus renders the overall status and the legs per status, "❌ *Matrix:* 3 legs · 2 ✅ 1 ❌".
+// With MatrixTotal the legs that haven't reported yet are counted as pending, "2/3 legs".
+func FormatMatrixStatus(t *Timeline) string {
+	legs := t.Legs()
+	counts := statusCounts(legs)
+	total := fmt.Sprintf("%d legs", len(legs))
+	if t.MatrixTotal > 0 {
+		counts[StatusPending] += max(t.MatrixTotal-len(legs), 0)
+		total = fmt.Sprintf("%d/%d legs", len(legs), t.MatrixTotal)
+	}
+	var parts []string
+	for _, status := range statusOrder {
+		if counts[status] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[status], status.Emoji()))
+		}
+	}
+	return fmt.Sprintf("%s *Matrix:* %s · %s", t.Overall().Emoji(), total, strings.Join(parts, " "))
+}
+
+// FormatLegDuration renders the leg duration, "-" while it is unknown
+func FormatLegDuration(leg Step, now time.Time) string {
+	if leg.Started.IsZero() && leg.Duration == 0 {
+		return "-"
+	}
+	return FormatDuration(leg.Elapsed(now))
+}
+
+// FormatMatrix renders the matrix status and a grid with one row per leg, followed by the
+// links of the legs that failed
+func FormatMatrix(t *Timeline) string {
+	legs := t.Legs()
+	width := 0
+	for _, leg := range legs {
+		width = max(width, len(leg.Name))
+	}
+	text := "\n" + FormatMatrixStatus(t) + "\n```\n"
+	for _, leg := range legs {
+		row := fmt.Sprintf("%s %-*s  %-7s", leg.Status.Emoji(), width, leg.Name, FormatLegDuration(leg, t.Updated))
+		if leg.Text != "" {
+			row += "  " + leg.Text
+		}
+		text += strings.TrimRight(row, " ") + "\n"
+	}
+	text += "```\n"
+	for _, leg := range legs {
+		if leg.URL != "" && (leg.Status == StatusFailure || leg.Status == StatusCancelled) {
+			text += fmt.Sprintf("%s *%s:* %s\n", leg.Status.Emoji(), leg.Name, leg.URL)
+		}
+	}
+	return text
+}
@@ -0,0 +1,153 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CommitInfo describes the commit/build a notification is about, empty fields are omitted
+type CommitInfo struct {
+	CommitSha    string `json:"commit_sha,omitempty"`
+	Branch       string `json:"branch,omitempty"`
+	WorkflowName string `json:"workflow_name,omitempty"`
+	CommitMsg    string `json:"commit_msg,omitempty"`
+	Author       string `json:"author,omitempty"`
+	AuthorEmail  string `json:"author_email,omitempty"`
+	Pusher       string `json:"pusher,omitempty"`
+	ImageTag     string `json:"image_tag,omitempty"`
+	CommitTime   string `json:"commit_time,omitempty"`
+	RunURL       string `json:"run_url,omitempty"`
+	CommitURL    string `json:"commit_url,omitempty"`
+	CompareURL   string `json:"compare_url,omitempty"`
+	PRNumber     string `json:"pr_number,omitempty"`
+	PRTitle      string `json:"pr_title,omitempty"`
+	PRURL        string `json:"pr_url,omitempty"`
+	ReleaseTag   string `json:"release_tag,omitempty"`
+	ReleaseURL   string `json:"release_url,omitempty"`
+	System       string `json:"system,omitempty"` // CI system named in the header, e.g. "GitLab Pipeline"
+}
+
+// defaultSystem names the CI system in the header when it wasn't detected
+const defaultSystem = "Github Workflow"
+
+// SystemName returns the CI system the header names
+func (c *CommitInfo) SystemName() string {
+	if c == nil || c.System == "" {
+		return defaultSystem
+	}
+	return c.System
+}
+
+// Message is a single notification line, optionally preceded by commit info
+type Message struct {
+	Text   string      `json:"text,omitempty"`
+	Commit *CommitInfo `json:"commit,omitempty"` // nil to send the line only
+	Time   time.Time   `json:"time"`             // defaults to now in the notifier's location
+	Step   string      `json:"step,omitempty"`   // named step the message updates, see Timeline.Add
+	Status Status      `json:"status,omitempty"` // status of Step, or of the line itself
+	Key    string      `json:"key,omitempty"`    // correlation key the sent message can be found by, see Finder
+	// Matrix marks a matrix leg result, the message is rendered as a grid of legs (FormatMatrix)
+	Matrix   bool          `json:"matrix,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"` // duration reported by the leg, instead of measuring Step
+	URL      string        `json:"url,omitempty"`      // link to the leg's job
+	// MatrixTotal is the number of legs the matrix has, 0 when only the reported legs are known
+	MatrixTotal int `json:"matrix_total,omitempty"`
+}
+
+// Timestamp formats the message time the way it is rendered in notifications
+func (m Message) Timestamp() string {
+	return m.Time.Format(time.DateTime)
+}
+
+// FormatText renders a new message: the commit info header followed by the message line
+func FormatText(m Message) string {
+	var msg string
+	if m.Commit != nil {
+		msg = FormatCommitInfo(m.Commit)
+	}
+	msg += fmt.Sprintf("* - %s:* %s \n", m.Text, m.Timestamp())
+	return msg
+}
+
+// FormatLine renders the line appended to an existing message on update
+func FormatLine(m Message) string {
+	return fmt.Sprintf("- *%s:* %s \n", m.Text, m.Timestamp())
+}
+
+// FormatCommitInfo renders the commit info header in Slack/Telegram markdown
+func FormatCommitInfo(c *CommitInfo) string {
+	msg := fmt.Sprintf("📦 *%s*\n\n", c.SystemName())
+
+	if c.CommitSha != "" {
+		msg += fmt.Sprintf("📌 *Commit:* `%s`\n", c.CommitSha)
+	}
+	if c.Branch != "" {
+		msg += fmt.Sprintf("🔖 *Branch:* `%s`\n", c.Branch)
+	}
+	if c.WorkflowName != "" {
+		msg += fmt.Sprintf("🛠️ *Workflow:* `%s`\n", c.WorkflowName)
+	}
+	if c.CommitMsg != "" {
+		msg += fmt.Sprintf("📝 *Message:* %s\n", c.CommitMsg)
+	}
+	if c.Author != "" {
+		if c.AuthorEmail != "" {
+			msg += fmt.Sprintf("👤 *Author:* %s (%s)\n", c.Author, c.AuthorEmail)
+		} else {
+			msg += fmt.Sprintf("👤 *Author:* %s\n", c.Author)
+		}
+	}
+	if c.Pusher != "" && c.Pusher != c.Author {
+		msg += fmt.Sprintf("🚀 *Triggered by:* %s\n", c.Pusher)
+	}
+	if c.PRNumber != "" {
+		msg += fmt.Sprintf("🔃 *Pull Request:* #%s %s\n", c.PRNumber, c.PRTitle)
+		if c.PRURL != "" {
+			msg += fmt.Sprintf("%s\n", c.PRURL)
+		}
+	}
+	if c.ReleaseTag != "" {
+		msg += fmt.Sprintf("🏷️ *Release:* %s\n", c.ReleaseTag)
+		if c.ReleaseURL != "" {
+			msg += fmt.Sprintf("%s\n", c.ReleaseURL)
+		}
+	}
+	if c.ImageTag != "" {
+		msg += fmt.Sprintf("🐳 *Image Tag:* %s\n", c.ImageTag)
+	}
+	if c.CommitTime != "" {
+		msg += fmt.Sprintf("🕗 *Commit Time:* %s\n", c.CommitTime)
+	}
+	if c.RunURL != "" {
+		msg += fmt.Sprintf("🔗 *Run:* %s\n", c.RunURL)
+	}
+	if c.CompareURL != "" {
+		msg += fmt.Sprintf("🔀 *Compare:* %s\n", c.CompareURL)
+	}
+	msg += "\n"
+	return msg
+}
+
+// FormatTopic renders what is deployed as a one line plain text channel topic,
+// e.g. "🚀 Live: v1.2.0 · abc1234 · main", empty when c has none of these fields
+func FormatTopic(c *CommitInfo) string {
+	version := c.ImageTag
+	if version == "" {
+		version = c.ReleaseTag
+	}
+	sha := c.CommitSha
+	if len(sha) > 7 {
+		sha = sha[:7]
+	}
+	var parts []string
+	for _, part := range []string{version, sha, c.Branch} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "🚀 Live: " + strings.Join(parts, " · ")
+}
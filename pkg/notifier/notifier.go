@@ -0,0 +1,209 @@
+// Package notifier sends CI/CD notifications through pluggable providers.
+// Providers live in their own packages (cicd-notifier/pkg/slack, cicd-notifier/pkg/telegram).
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrUnsupported is returned by providers for operations their platform can't perform
+var ErrUnsupported = errors.New("operation not supported by provider")
+
+// Ref identifies a sent message
+type Ref struct {
+	ChannelID string
+	MessageID string
+}
+
+// Provider is implemented by every notification channel
+type Provider interface {
+	// Send posts a new message to channelID
+	Send(ctx context.Context, channelID string, msg Message) (Ref, error)
+	// Update appends msg to the referenced message, the returned Ref may differ if the message was re-posted
+	Update(ctx context.Context, ref Ref, msg Message) (Ref, error)
+	// Delete removes the referenced message
+	Delete(ctx context.Context, ref Ref) error
+	// Verify checks the credentials and returns who they belong to
+	Verify(ctx context.Context, channelID string) (string, error)
+}
+
+// Replier is implemented by providers that can post into the thread of a message
+type Replier interface {
+	// Reply posts msg as a reply to the referenced message and returns the reply
+	Reply(ctx context.Context, ref Ref, msg Message) (Ref, error)
+}
+
+// Finder is implemented by providers that can look up a message by the key it was sent with
+type Finder interface {
+	// Find returns the oldest message in channelID sent with key, ok is false when there is none.
+	// Jobs racing to send the message converge on the oldest one.
+	Find(ctx context.Context, channelID, key string) (ref Ref, ok bool, err error)
+}
+
+// Reactor is implemented by providers that can react to messages with emoji
+type Reactor interface {
+	// React removes the reactions in remove from the referenced message and adds the ones in add
+	React(ctx context.Context, ref Ref, add, remove []string) error
+}
+
+// Pinner is implemented by providers that can pin messages and set the channel topic
+type Pinner interface {
+	// Pin pins the referenced message, with exclusive the bot's previously pinned message is unpinned
+	Pin(ctx context.Context, ref Ref, exclusive bool) error
+	// Unpin unpins the referenced message
+	Unpin(ctx context.Context, ref Ref) error
+	// SetTopic replaces the channel topic (Slack) or chat description (Telegram)
+	SetTopic(ctx context.Context, channelID, topic string) error
+}
+
+// Cleaner is implemented by providers that can find and remove their own old messages
+type Cleaner interface {
+	// Cleanup deletes the bot's messages in channelID sent before the cutoff and returns how many were deleted
+	Cleanup(ctx context.Context, channelID string, before time.Time) (int, error)
+}
+
+// Notifier sends messages to a single channel through a Provider
+type Notifier struct {
+	provider  Provider
+	channelID string
+	location  *time.Location
+	now       func() time.Time
+}
+
+// Option configures a Notifier
+type Option func(*Notifier)
+
+// WithLocation sets the timezone message timestamps are rendered in, defaults to UTC
+func WithLocation(loc *time.Location) Option {
+	return func(n *Notifier) {
+		n.location = loc
+	}
+}
+
+// WithClock replaces time.Now, mostly useful in tests
+func WithClock(now func() time.Time) Option {
+	return func(n *Notifier) {
+		n.now = now
+	}
+}
+
+// New creates a Notifier posting to channelID through provider
+func New(provider Provider, channelID string, opts ...Option) *Notifier {
+	n := &Notifier{
+		provider:  provider,
+		channelID: channelID,
+		location:  time.UTC,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n
+}
+
+// Send posts a new message
+func (n *Notifier) Send(ctx context.Context, msg Message) (Ref, error) {
+	return n.provider.Send(ctx, n.channelID, n.stamp(msg))
+}
+
+// Update appends msg to the message msgID
+func (n *Notifier) Update(ctx context.Context, msgID string, msg Message) (Ref, error) {
+	return n.provider.Update(ctx, n.ref(msgID), n.stamp(msg))
+}
+
+// Find returns the message sent with key, ok is false when there is none
+func (n *Notifier) Find(ctx context.Context, key string) (Ref, bool, error) {
+	finder, ok := n.provider.(Finder)
+	if !ok {
+		return Ref{}, false, fmt.Errorf("finding messages by key: %w", ErrUnsupported)
+	}
+	return finder.Find(ctx, n.channelID, key)
+}
+
+// Reply posts msg in the thread of the message msgID, the message itself is left untouched
+func (n *Notifier) Reply(ctx context.Context, msgID string, msg Message) (Ref, error) {
+	replier, ok := n.provider.(Replier)
+	if !ok {
+		return Ref{}, fmt.Errorf("thread replies: %w", ErrUnsupported)
+	}
+	return replier.Reply(ctx, n.ref(msgID), n.stamp(msg))
+}
+
+// React swaps the bot's emoji reactions on the message msgID
+func (n *Notifier) React(ctx context.Context, msgID string, add, remove []string) error {
+	reactor, ok := n.provider.(Reactor)
+	if !ok {
+		return fmt.Errorf("reactions: %w", ErrUnsupported)
+	}
+	return reactor.React(ctx, n.ref(msgID), add, remove)
+}
+
+// Pin pins the message msgID, unpinning the bot's previous pin when exclusive is set
+func (n *Notifier) Pin(ctx context.Context, msgID string, exclusive bool) error {
+	pinner, err := n.pinner()
+	if err != nil {
+		return err
+	}
+	return pinner.Pin(ctx, n.ref(msgID), exclusive)
+}
+
+// Unpin unpins the message msgID
+func (n *Notifier) Unpin(ctx context.Context, msgID string) error {
+	pinner, err := n.pinner()
+	if err != nil {
+		return err
+	}
+	return pinner.Unpin(ctx, n.ref(msgID))
+}
+
+// SetTopic replaces the channel topic
+func (n *Notifier) SetTopic(ctx context.Context, topic string) error {
+	pinner, err := n.pinner()
+	if err != nil {
+		return err
+	}
+	return pinner.SetTopic(ctx, n.channelID, topic)
+}
+
+func (n *Notifier) pinner() (Pinner, error) {
+	pinner, ok := n.provider.(Pinner)
+	if !ok {
+		return nil, fmt.Errorf("pins and topics: %w", ErrUnsupported)
+	}
+	return pinner, nil
+}
+
+// Delete removes the message msgID
+func (n *Notifier) Delete(ctx context.Context, msgID string) error {
+	return n.provider.Delete(ctx, n.ref(msgID))
+}
+
+// Cleanup deletes the bot's own messages older than age
+func (n *Notifier) Cleanup(ctx context.Context, age time.Duration) (int, error) {
+	cleaner, ok := n.provider.(Cleaner)
+	if !ok {
+		return 0, fmt.Errorf("message cleanup: %w", ErrUnsupported)
+	}
+	return cleaner.Cleanup(ctx, n.channelID, n.now().Add(-age))
+}
+
+// Verify checks the provider credentials
+func (n *Notifier) Verify(ctx context.Context) (string, error) {
+	return n.provider.Verify(ctx, n.channelID)
+}
+
+func (n *Notifier) ref(msgID string) Ref {
+	return Ref{ChannelID: n.channelID, MessageID: msgID}
+}
+
+// stamp sets the message time to now when missing and converts it to the notifier's location
+func (n *Notifier) stamp(msg Message) Message {
+	if msg.Time.IsZero() {
+		msg.Time = n.now()
+	}
+	msg.Time = msg.Time.In(n.location)
+	return msg
+}
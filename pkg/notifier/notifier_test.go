@@ -0,0 +1,78 @@
+package notifier
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type recordingProvider struct {
+	sent    []Message
+	updated []Ref
+}
+
+func (p *recordingProvider) Send(_ context.Context, channelID string, msg Message) (Ref, error) {
+	p.sent = append(p.sent, msg)
+	return Ref{ChannelID: channelID, MessageID: "1"}, nil
+}
+
+func (p *recordingProvider) Update(_ context.Context, ref Ref, msg Message) (Ref, error) {
+	p.updated = append(p.updated, ref)
+	return Ref{ChannelID: ref.ChannelID, MessageID: "2"}, nil
+}
+
+func (p *recordingProvider) Delete(context.Context, Ref) error { return nil }
+
+func (p *recordingProvider) Verify(context.Context, string) (string, error) { return "ok", nil }
+
+func TestNotifierSend(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	clock := func() time.Time { return time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC) }
+	p := &recordingProvider{}
+	n := New(p, "C123", WithLocation(loc), WithClock(clock))
+
+	ref, err := n.Send(context.Background(), Message{Text: "Deploy started"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if ref != (Ref{ChannelID: "C123", MessageID: "1"}) {
+		t.Errorf("Send() ref = %+v", ref)
+	}
+	if got := FormatText(p.sent[0]); got != "* - Deploy started:* 2023-01-01 12:00:00 \n" {
+		t.Errorf("FormatText() = %q", got)
+	}
+
+	if _, err := n.Update(context.Background(), "1", Message{Text: "Deployed"}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if p.updated[0] != (Ref{ChannelID: "C123", MessageID: "1"}) {
+		t.Errorf("Update() ref = %+v", p.updated[0])
+	}
+}
+
+func TestFormatCommitInfo(t *testing.T) {
+	tests := []struct {
+		system   string
+		expected string
+	}{
+		{"", "📦 *Github Workflow*\n\n📌 *Commit:* `abc123`\n🔖 *Branch:* `main`\n👤 *Author:* user\n\n"},
+		{"GitLab Pipeline", "📦 *GitLab Pipeline*\n\n📌 *Commit:* `abc123`\n🔖 *Branch:* `main`\n👤 *Author:* user\n\n"},
+	}
+	for _, tt := range tests {
+		info := &CommitInfo{CommitSha: "abc123", Branch: "main", Author: "user", System: tt.system}
+		if got := FormatCommitInfo(info); got != tt.expected {
+			t.Errorf("FormatCommitInfo() = %q, expected %q", got, tt.expected)
+		}
+	}
+}
+
+func TestFormatTopic(t *testing.T) {
+	info := &CommitInfo{CommitSha: "abc1234567890", Branch: "main", ImageTag: "v1.2.0"}
+
+	if got := FormatTopic(info); got != "🚀 Live: v1.2.0 · abc1234 · main" {
+		t.Errorf("FormatTopic() = %q", got)
+	}
+	if got := FormatTopic(&CommitInfo{}); got != "" {
+		t.Errorf("FormatTopic() without version = %q, expected empty", got)
+	}
+}
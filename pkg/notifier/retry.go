@@ -0,0 +1,120 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy configures retries of failed provider calls
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts, 1 or less disables retries
+	BaseDelay   time.Duration // first backoff delay, doubled on every attempt
+	MaxDelay    time.Duration // upper bound for backoff, a longer Retry-After gives up instead
+}
+
+// DefaultRetryPolicy retries twice with jittered backoff starting at one second
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+
+// RetryError marks a failed provider call that may succeed when retried
+type RetryError struct {
+	Err error
+	// RetryAfter is the delay requested by the server (Slack Retry-After, Telegram retry_after)
+	RetryAfter time.Duration
+	// NotProcessed is true when the server certainly didn't act on the request (rate limited,
+	// connection refused), so non-idempotent calls like posting a message can be retried without duplicates
+	NotProcessed bool
+}
+
+func (e *RetryError) Error() string { return e.Err.Error() }
+
+func (e *RetryError) Unwrap() error { return e.Err }
+
+// NetworkError wraps transport failures in a RetryError, other errors are returned unchanged
+// Failures to connect are known not to have reached the server, anything later (timeouts, resets) is ambiguous
+func NetworkError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var retryErr *RetryError
+	if errors.As(err, &retryErr) {
+		return err
+	}
+	// A cancelled run must not be retried
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return &RetryError{Err: err, NotProcessed: true}
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return &RetryError{Err: err, NotProcessed: true}
+	}
+	// *url.Error implements net.Error, this covers resets and EOFs from the HTTP client
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return &RetryError{Err: err}
+	}
+	return err
+}
+
+// Do runs op until it succeeds, fails with an error that isn't a RetryError, or attempts run out.
+// Calls that aren't idempotent are only retried when the server certainly didn't process them.
+func (p RetryPolicy) Do(ctx context.Context, idempotent bool, op func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil {
+			return nil
+		}
+		var retryErr *RetryError
+		if !errors.As(err, &retryErr) || attempt >= p.MaxAttempts {
+			return err
+		}
+		if !idempotent && !retryErr.NotProcessed {
+			return err
+		}
+		delay := p.backoff(attempt)
+		if retryErr.RetryAfter > 0 {
+			if p.MaxDelay > 0 && retryErr.RetryAfter > p.MaxDelay {
+				return err
+			}
+			delay = retryErr.RetryAfter
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// Wait sleeps the backoff before attempt+1, it returns early with the error of a done context
+func (p RetryPolicy) Wait(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(p.backoff(attempt))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// backoff returns the jittered delay before the next attempt: a random value in [d/2, d]
+// where d doubles from BaseDelay on every attempt, capped at MaxDelay
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if p.MaxDelay > 0 && (d > p.MaxDelay || d <= 0) {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
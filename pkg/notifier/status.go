@@ -0,0 +1,141 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Status is the state of a named pipeline step
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusSuccess   Status = "success"
+	StatusFailure   Status = "failure"
+	StatusCancelled Status = "cancelled"
+	StatusSkipped   Status = "skipped"
+)
+
+var statusEmoji = map[Status]string{
+	StatusPending:   "🕓",
+	StatusRunning:   "⏳",
+	StatusSuccess:   "✅",
+	StatusFailure:   "❌",
+	StatusCancelled: "🚫",
+	StatusSkipped:   "⏭️",
+}
+
+// ParseStatus validates a status input, the empty string means no status
+func ParseStatus(s string) (Status, error) {
+	status := Status(strings.ToLower(strings.TrimSpace(s)))
+	if status == "" {
+		return "", nil
+	}
+	if _, ok := statusEmoji[status]; !ok {
+		return "", fmt.Errorf("unknown status %q, expected pending, running, success, failure, cancelled or skipped", s)
+	}
+	return status, nil
+}
+
+// Emoji returns the icon the status is rendered with
+func (s Status) Emoji() string {
+	return statusEmoji[s]
+}
+
+// Done reports whether the step has finished, successfully or not
+func (s Status) Done() bool {
+	return s != "" && s != StatusPending && s != StatusRunning
+}
+
+// Elapsed returns how long the step ran, or has been running at now.
+// A duration reported by the step wins over the measured one.
+func (s Step) Elapsed(now time.Time) time.Duration {
+	switch {
+	case s.Duration > 0:
+		return s.Duration
+	case s.Started.IsZero():
+		return 0
+	case s.Status.Done():
+		return s.Finished.Sub(s.Started)
+	case s.Status == StatusRunning:
+		return now.Sub(s.Started)
+	}
+	return 0
+}
+
+// Title renders the step name and text with its status emoji, "✅ *build:* Image pushed"
+func (s Step) Title() string {
+	label, text := s.Name, s.Text
+	if label == "" {
+		label, text = text, ""
+	}
+	title := fmt.Sprintf("%s *%s:*", s.Status.Emoji(), label)
+	if text != "" {
+		title += " " + text
+	}
+	return title
+}
+
+// Progress describes the step duration, "running for 1m2s" or "took 2m5s", empty when unknown
+func (s Step) Progress(now time.Time) string {
+	elapsed := s.Elapsed(now)
+	switch {
+	case s.Duration > 0 && s.Status.Done():
+		return "took " + FormatDuration(elapsed)
+	case s.Started.IsZero():
+		return ""
+	case s.Status == StatusRunning:
+		return "running for " + FormatDuration(elapsed)
+	case s.Status.Done():
+		return "took " + FormatDuration(elapsed)
+	}
+	return ""
+}
+
+// FormatDuration renders d rounded to seconds, "2m5s"
+func FormatDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
+
+// FormatStatusLine renders a step with a status, the duration is measured at now for running steps
+func FormatStatusLine(s Step, now time.Time) string {
+	line := s.Title()
+	if progress := s.Progress(now); progress != "" {
+		line += fmt.Sprintf(" _(%s)_", progress)
+	}
+	return line + " \n"
+}
+
+// FormatSummary renders the per-step duration table and the total pipeline time
+func FormatSummary(t *Timeline) string {
+	width := 0
+	for _, step := range t.Steps {
+		if step.Name != "" && len(step.Name) > width {
+			width = len(step.Name)
+		}
+	}
+	summary := "\n⏱️ *Durations:*\n```\n"
+	for _, step := range t.Steps {
+		if step.Name == "" {
+			continue
+		}
+		duration := "-"
+		if !step.Started.IsZero() || step.Duration > 0 {
+			duration = FormatDuration(step.Elapsed(t.Updated))
+		}
+		summary += fmt.Sprintf("%-*s  %-9s  %s\n", width, step.Name, step.Status, duration)
+	}
+	summary += "```\n"
+	summary += fmt.Sprintf("*Total:* %s \n", FormatDuration(t.Total()))
+	return summary
+}
+
+// FormatUpdate renders the line a single update adds, with its status when set
+func FormatUpdate(m Message) string {
+	if m.Status == "" {
+		return FormatLine(m)
+	}
+	return FormatStatusLine(m.step(), m.Time)
+}
@@ -0,0 +1,113 @@
+package notifier
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// FileStore keeps timelines in a JSON file keyed by channel and message ID,
+// for platforms that can't store them with the message (Telegram)
+type FileStore struct {
+	Path string
+}
+
+func (s FileStore) key(ref Ref) string {
+	return ref.ChannelID + "/" + ref.MessageID
+}
+
+func (s FileStore) read() (map[string]*Timeline, error) {
+	timelines := make(map[string]*Timeline)
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return timelines, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file- %w", err)
+	}
+	if err := json.Unmarshal(data, &timelines); err != nil {
+		return nil, fmt.Errorf("failed to parse state file- %w", err)
+	}
+	return timelines, nil
+}
+
+func (s FileStore) write(timelines map[string]*Timeline) error {
+	data, err := json.MarshalIndent(timelines, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.Path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state file- %w", err)
+	}
+	return nil
+}
+
+// List returns every stored timeline of channelID by message ref
+func (s FileStore) List(channelID string) (map[Ref]*Timeline, error) {
+	timelines, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	refs := make(map[Ref]*Timeline)
+	for key, t := range timelines {
+		if chId, msgId, ok := strings.Cut(key, "/"); ok && chId == channelID {
+			refs[Ref{ChannelID: chId, MessageID: msgId}] = t
+		}
+	}
+	return refs, nil
+}
+
+// Find returns the oldest message of channelID whose timeline has key, ok is false when there is none.
+// Messages sent at the same time are ordered by ID, so every reader picks the same one.
+func (s FileStore) Find(channelID, key string) (Ref, bool, error) {
+	timelines, err := s.List(channelID)
+	if err != nil {
+		return Ref{}, false, err
+	}
+	var found Ref
+	var sent time.Time
+	for ref, t := range timelines {
+		if t.Key != key {
+			continue
+		}
+		if found == (Ref{}) || t.Sent().Before(sent) || t.Sent().Equal(sent) && ref.MessageID < found.MessageID {
+			found, sent = ref, t.Sent()
+		}
+	}
+	return found, found != Ref{}, nil
+}
+
+// Load returns the timeline of ref, nil when the file has none
+func (s FileStore) Load(ref Ref) (*Timeline, error) {
+	timelines, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return timelines[s.key(ref)], nil
+}
+
+// Save stores the timeline of ref, other entries are kept
+func (s FileStore) Save(ref Ref, t *Timeline) error {
+	timelines, err := s.read()
+	if err != nil {
+		return err
+	}
+	timelines[s.key(ref)] = t
+	return s.write(timelines)
+}
+
+// Remove drops the timeline of ref
+func (s FileStore) Remove(ref Ref) error {
+	timelines, err := s.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := timelines[s.key(ref)]; !ok {
+		return nil
+	}
+	delete(timelines, s.key(ref))
+	return s.write(timelines)
+}
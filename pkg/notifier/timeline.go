@@ -0,0 +1,215 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Timeline is the structured content of a deployment message: the commit info and every
+// line sent so far. Providers persist it with the message and re-render it on each update,
+// so the rendering never depends on text read back from the platform.
+type Timeline struct {
+	Key     string      `json:"key,omitempty"`    // correlation key the message is found by
+	Matrix  bool        `json:"matrix,omitempty"` // named steps are matrix legs, rendered as a grid
+	Commit  *CommitInfo `json:"commit,omitempty"`
+	Steps   []Step      `json:"steps"`
+	Updated time.Time   `json:"updated,omitzero"` // time of the latest message, running steps are measured against it
+	// MatrixTotal is the number of legs expected to report, see Overall
+	MatrixTotal int `json:"matrix_total,omitempty"`
+}
+
+// Step is one line of a timeline, named steps with a status are updated in place
+type Step struct {
+	Name     string        `json:"name,omitempty"`
+	Text     string        `json:"text"`
+	Status   Status        `json:"status,omitempty"`
+	Time     time.Time     `json:"time"`
+	Started  time.Time     `json:"started,omitzero"`
+	Finished time.Time     `json:"finished,omitzero"`
+	Duration time.Duration `json:"duration,omitempty"` // reported by the step itself, see Elapsed
+	URL      string        `json:"url,omitempty"`
+}
+
+// NewTimeline starts a timeline with the first message
+func NewTimeline(msg Message) *Timeline {
+	t := &Timeline{Key: msg.Key, Matrix: msg.Matrix, Commit: msg.Commit}
+	t.Add(msg)
+	return t
+}
+
+// Sent returns when the first message of the timeline was sent
+func (t *Timeline) Sent() time.Time {
+	if len(t.Steps) == 0 {
+		return t.Updated
+	}
+	return t.Steps[0].Time
+}
+
+// Add records msg: a message for a step that is already on the timeline updates it,
+// anything else is appended as a new step
+func (t *Timeline) Add(msg Message) {
+	t.Updated = msg.Time
+	t.Matrix = t.Matrix || msg.Matrix
+	if msg.MatrixTotal > 0 {
+		t.MatrixTotal = msg.MatrixTotal
+	}
+	if msg.Step != "" {
+		for i := range t.Steps {
+			if t.Steps[i].Name == msg.Step {
+				t.Steps[i].update(msg)
+				return
+			}
+		}
+	}
+	t.Steps = append(t.Steps, msg.step())
+}
+
+// step converts msg to a new timeline step
+func (m Message) step() Step {
+	s := Step{Name: m.Step, Text: m.Text, Time: m.Time}
+	s.update(m)
+	return s
+}
+
+// update applies the status of msg, the step starts when it is running and ends with a final status
+func (s *Step) update(msg Message) {
+	if msg.Text != "" {
+		s.Text = msg.Text
+	}
+	if msg.Duration > 0 {
+		s.Duration = msg.Duration
+	}
+	if msg.URL != "" {
+		s.URL = msg.URL
+	}
+	if msg.Status == "" {
+		return
+	}
+	s.Status = msg.Status
+	if msg.Status == StatusRunning && s.Started.IsZero() {
+		s.Started = msg.Time
+	}
+	if msg.Status.Done() {
+		s.Finished = msg.Time
+	}
+}
+
+// Done reports whether the timeline tracks named steps and all of them have finished
+func (t *Timeline) Done() bool {
+	named := false
+	for _, step := range t.Steps {
+		if step.Name == "" {
+			continue
+		}
+		if !step.Status.Done() {
+			return false
+		}
+		named = true
+	}
+	return named
+}
+
+// Total returns the pipeline time from the first message to the latest one
+func (t *Timeline) Total() time.Duration {
+	if len(t.Steps) == 0 {
+		return 0
+	}
+	return t.Updated.Sub(t.Steps[0].Time)
+}
+
+// Messages returns the steps as messages, the first one carries the commit info
+func (t *Timeline) Messages() []Message {
+	msgs := make([]Message, len(t.Steps))
+	for i, step := range t.Steps {
+		msgs[i] = Message{Text: step.Text, Time: step.Time, Step: step.Name, Status: step.Status,
+			Matrix: t.Matrix && step.Name != "", Duration: step.Duration, URL: step.URL}
+	}
+	if len(msgs) > 0 {
+		msgs[0].Commit = t.Commit
+	}
+	return msgs
+}
+
+// FormatTimeline renders the whole timeline, plain steps as FormatText and FormatLine do,
+// steps with a status with their emoji and duration, and the summary once all steps are done.
+// The legs of a matrix are rendered as a grid after the plain steps instead.
+func FormatTimeline(t *Timeline) string {
+	var text string
+	if t.Commit != nil {
+		text = FormatCommitInfo(t.Commit)
+	}
+	for i, step := range t.Steps {
+		msg := Message{Text: step.Text, Time: step.Time}
+		switch {
+		case t.Matrix && step.Name != "":
+			continue
+		case step.Status != "":
+			text += FormatStatusLine(step, t.Updated)
+		case i == 0:
+			text += FormatText(msg)
+		default:
+			text += FormatLine(msg)
+		}
+	}
+	if t.Matrix {
+		return text + FormatMatrix(t)
+	}
+	if t.Done() {
+		text += FormatSummary(t)
+	}
+	return text
+}
+
+// MarshalPayload converts the timeline to a JSON object, e.g. for Slack message metadata
+func (t *Timeline) MarshalPayload() (map[string]any, error) {
+	return marshalPayload(t)
+}
+
+// UnmarshalTimeline restores a timeline stored with MarshalPayload
+func UnmarshalTimeline(payload map[string]any) (*Timeline, error) {
+	var t Timeline
+	if err := unmarshalPayload(payload, &t); err != nil {
+		return nil, fmt.Errorf("failed to decode timeline- %w", err)
+	}
+	if len(t.Steps) == 0 {
+		return nil, fmt.Errorf("failed to decode timeline- no steps")
+	}
+	return &t, nil
+}
+
+// MarshalPayload converts the message to a JSON object, e.g. for the Slack metadata an update is recorded in
+func (m Message) MarshalPayload() (map[string]any, error) {
+	return marshalPayload(m)
+}
+
+// UnmarshalMessage restores a message stored with MarshalPayload
+func UnmarshalMessage(payload map[string]any) (Message, error) {
+	var m Message
+	if err := unmarshalPayload(payload, &m); err != nil {
+		return Message{}, fmt.Errorf("failed to decode message- %w", err)
+	}
+	return m, nil
+}
+
+// marshalPayload round-trips v through JSON into a generic object
+func marshalPayload(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// unmarshalPayload decodes a generic object produced by marshalPayload into v
+func unmarshalPayload(payload map[string]any, v any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
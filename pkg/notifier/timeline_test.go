@@ -0,0 +1,184 @@
+package notifier
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTimeline(t *testing.T) {
+	start := time.Date(2023, 1, 1, 12, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60))
+	first := Message{Text: "Deploy started", Commit: &CommitInfo{CommitSha: "abc123"}, Time: start}
+	second := Message{Text: "Deployed", Time: start.Add(5 * time.Minute)}
+
+	timeline := NewTimeline(first)
+	timeline.Add(second)
+	expected := FormatText(first) + FormatLine(second)
+	if got := FormatTimeline(timeline); got != expected {
+		t.Errorf("FormatTimeline() = %q, expected %q", got, expected)
+	}
+
+	// Round trip through the JSON object stored as Slack metadata
+	payload, err := timeline.MarshalPayload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := UnmarshalTimeline(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := FormatTimeline(restored); got != expected {
+		t.Errorf("restored FormatTimeline() = %q, expected %q", got, expected)
+	}
+
+	if _, err := UnmarshalTimeline(map[string]any{"steps": []any{}}); err == nil {
+		t.Error("UnmarshalTimeline() without steps error = nil")
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	store := FileStore{Path: filepath.Join(t.TempDir(), "state.json")}
+	ref := Ref{ChannelID: "-100", MessageID: "42"}
+	other := Ref{ChannelID: "-100", MessageID: "43"}
+
+	if got, err := store.Load(ref); err != nil || got != nil {
+		t.Fatalf("Load() on a missing file = %v, %v", got, err)
+	}
+	timeline := NewTimeline(Message{Text: "Deploy started", Time: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)})
+	if err := store.Save(ref, timeline); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(other, timeline); err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.Load(ref)
+	if err != nil || got == nil || FormatTimeline(got) != FormatTimeline(timeline) {
+		t.Fatalf("Load() = %v, %v", got, err)
+	}
+	if err := store.Remove(ref); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := store.Load(ref); got != nil {
+		t.Errorf("Load() after Remove() = %v", got)
+	}
+	if got, _ := store.Load(other); got == nil {
+		t.Error("Remove() dropped another message")
+	}
+}
+
+func TestFileStoreFind(t *testing.T) {
+	store := FileStore{Path: filepath.Join(t.TempDir(), "state.json")}
+	start := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	messages := map[Ref]Message{
+		{ChannelID: "-100", MessageID: "1"}: {Text: "Deploy", Key: "v1.2.0", Time: start},
+		{ChannelID: "-100", MessageID: "2"}: {Text: "Deploy", Key: "v1.2.0", Time: start.Add(time.Hour)},
+		{ChannelID: "-100", MessageID: "3"}: {Text: "Deploy", Key: "v1.3.0", Time: start.Add(2 * time.Hour)},
+		{ChannelID: "-200", MessageID: "4"}: {Text: "Deploy", Key: "v1.2.0", Time: start.Add(3 * time.Hour)},
+	}
+	for ref, msg := range messages {
+		if err := store.Save(ref, NewTimeline(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ref, ok, err := store.Find("-100", "v1.2.0")
+	if err != nil || !ok || ref.MessageID != "1" {
+		t.Errorf("Find() = %+v, %t, %v, expected the oldest message 1", ref, ok, err)
+	}
+	if _, ok, _ := store.Find("-100", "v2.0.0"); ok {
+		t.Error("Find() of an unknown key found a message")
+	}
+}
+
+func TestTimelineSteps(t *testing.T) {
+	start := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+
+	timeline := NewTimeline(Message{Text: "Deploy started", Time: at(0)})
+	timeline.Add(Message{Step: "build", Status: StatusRunning, Text: "Building image", Time: at(1)})
+	timeline.Add(Message{Step: "deploy", Status: StatusPending, Time: at(1)})
+
+	expected := "* - Deploy started:* 2023-01-01 12:00:00 \n" +
+		"⏳ *build:* Building image _(running for 0s)_ \n" +
+		"🕓 *deploy:* \n"
+	if got := FormatTimeline(timeline); got != expected {
+		t.Errorf("FormatTimeline() running = %q, expected %q", got, expected)
+	}
+
+	timeline.Add(Message{Step: "build", Status: StatusSuccess, Text: "Image pushed", Time: at(3)})
+	timeline.Add(Message{Step: "deploy", Status: StatusRunning, Time: at(4)})
+	timeline.Add(Message{Step: "deploy", Status: StatusFailure, Time: at(9)})
+
+	if len(timeline.Steps) != 3 {
+		t.Fatalf("Steps = %d, named steps must be updated in place", len(timeline.Steps))
+	}
+	expected = "* - Deploy started:* 2023-01-01 12:00:00 \n" +
+		"✅ *build:* Image pushed _(took 2m0s)_ \n" +
+		"❌ *deploy:* _(took 5m0s)_ \n" +
+		"\n⏱️ *Durations:*\n```\n" +
+		"build   success    2m0s\n" +
+		"deploy  failure    5m0s\n" +
+		"```\n*Total:* 9m0s \n"
+	if got := FormatTimeline(timeline); got != expected {
+		t.Errorf("FormatTimeline() done = %q, expected %q", got, expected)
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	if status, err := ParseStatus(" Success "); err != nil || status != StatusSuccess {
+		t.Errorf("ParseStatus() = %q, %v", status, err)
+	}
+	if status, err := ParseStatus(""); err != nil || status != "" {
+		t.Errorf("ParseStatus(\"\") = %q, %v", status, err)
+	}
+	if _, err := ParseStatus("done"); err == nil {
+		t.Error("ParseStatus(\"done\") error = nil")
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	start := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	timeline := NewTimeline(Message{Text: "Tests started", Key: "run-42", Time: start})
+	timeline.Add(Message{Step: "linux", Status: StatusSuccess, Matrix: true, Duration: 3*time.Minute + 12*time.Second, Time: start.Add(4 * time.Minute)})
+	timeline.Add(Message{Step: "windows", Status: StatusRunning, Matrix: true, Time: start.Add(5 * time.Minute)})
+
+	if got := timeline.Overall(); got != StatusRunning {
+		t.Errorf("Overall() with a running leg = %q", got)
+	}
+	timeline.Add(Message{Step: "windows", Status: StatusFailure, Text: "3 failed", Matrix: true,
+		URL: "https://ci/jobs/2", Time: start.Add(6 * time.Minute)})
+	if got := timeline.Overall(); got != StatusFailure {
+		t.Errorf("Overall() with a failed leg = %q", got)
+	}
+
+	expected := "* - Tests started:* 2023-01-01 12:00:00 \n" +
+		"\n❌ *Matrix:* 2 legs · 1 ❌ 1 ✅\n" +
+		"```\n" +
+		"✅ linux    3m12s\n" +
+		"❌ windows  1m0s     3 failed\n" +
+		"```\n" +
+		"❌ *windows:* https://ci/jobs/2\n"
+	if got := FormatTimeline(timeline); got != expected {
+		t.Errorf("FormatTimeline() = %q, expected %q", got, expected)
+	}
+}
+
+func TestMatrixTotal(t *testing.T) {
+	start := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	timeline := NewTimeline(Message{Text: "Tests started", Time: start})
+	timeline.Add(Message{Step: "linux", Status: StatusSuccess, Matrix: true, MatrixTotal: 3, Time: start.Add(time.Minute)})
+	timeline.Add(Message{Step: "macos", Status: StatusSuccess, Matrix: true, MatrixTotal: 3, Time: start.Add(2 * time.Minute)})
+
+	// Every leg that reported passed, but windows hasn't reported yet
+	if got := timeline.Overall(); got != StatusRunning {
+		t.Errorf("Overall() with a missing leg = %q", got)
+	}
+	expected := StatusRunning.Emoji() + " *Matrix:* 2/3 legs · 1 " + StatusPending.Emoji() + " 2 " + StatusSuccess.Emoji()
+	if got := FormatMatrixStatus(timeline); got != expected {
+		t.Errorf("FormatMatrixStatus() = %q, expected %q", got, expected)
+	}
+	timeline.Add(Message{Step: "windows", Status: StatusSuccess, Matrix: true, MatrixTotal: 3, Time: start.Add(3 * time.Minute)})
+	if got := timeline.Overall(); got != StatusSuccess {
+		t.Errorf("Overall() with all legs reported = %q", got)
+	}
+}
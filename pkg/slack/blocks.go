@@ -0,0 +1,285 @@
+package slack
+
+import (
+	"cicd-notifier/pkg/notifier"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/slack-go/slack"
+)
+
+// headerBlocks renders the header, commit fields and commit message. Without commit info there is
+// no header, as in the text rendering, rather than one naming a CI system that wasn't detected.
+func headerBlocks(info *notifier.CommitInfo) []slack.Block {
+	if info == nil {
+		return nil
+	}
+	blocks := []slack.Block{
+		slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType, "📦 "+info.SystemName(), true, false)),
+	}
+	if fields := commitFields(info); len(fields) > 0 {
+		blocks = append(blocks, slack.NewSectionBlock(nil, fields, nil))
+	}
+	if info.CommitMsg != "" {
+		blocks = append(blocks, slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, sectionText(fmt.Sprintf("📝 *Message:* %s", info.CommitMsg)), false, false), nil, nil))
+	}
+	return blocks
+}
+
+// linkBlocks renders the link buttons, if any
+func linkBlocks(info *notifier.CommitInfo) []slack.Block {
+	if info == nil {
+		return nil
+	}
+	buttons := linkButtons(info)
+	if len(buttons) == 0 {
+		return nil
+	}
+	return []slack.Block{slack.NewActionBlock("links", buttons...)}
+}
+
+// UpdateBlocks returns the section and context blocks describing a single message line
+func UpdateBlocks(msg, timestamp string) []slack.Block {
+	return []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, sectionText(fmt.Sprintf("*%s*", msg)), false, false), nil, nil),
+		slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("🕗 %s", timestamp), false, false)),
+	}
+}
+
+// StepBlocks renders a timeline step, steps with a status show their emoji and duration at now
+func StepBlocks(step notifier.Step, now time.Time) []slack.Block {
+	timestamp := step.Time.Format(time.DateTime)
+	if step.Status == "" {
+		return UpdateBlocks(step.Text, timestamp)
+	}
+	detail := fmt.Sprintf("🕗 %s", timestamp)
+	if progress := step.Progress(now); progress != "" {
+		detail += fmt.Sprintf(" · ⏱️ %s", progress)
+	}
+	return []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, sectionText(step.Title()), false, false), nil, nil),
+		slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, detail, false, false)),
+	}
+}
+
+// maxBlocks is the number of blocks Slack allows in a message
+const maxBlocks = 50
+
+// maxSectionText is the number of characters Slack allows in a section text
+const maxSectionText = 3000
+
+// TimelineBlocks renders the header, every step of the timeline, the duration summary
+// once all steps are done, and the link buttons. When the steps don't fit in maxBlocks
+// the oldest ones are collapsed into a single section.
+func TimelineBlocks(t *notifier.Timeline) []slack.Block {
+	header := headerBlocks(t.Commit)
+	var steps []notifier.Step
+	for _, step := range t.Steps {
+		if !(t.Matrix && step.Name != "") {
+			steps = append(steps, step)
+		}
+	}
+	var tail []slack.Block
+	if t.Matrix {
+		tail = MatrixBlocks(t)
+	} else if t.Done() {
+		summary := strings.TrimPrefix(notifier.FormatSummary(t), "\n")
+		tail = []slack.Block{slack.NewDividerBlock(),
+			slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, sectionText(summary), false, false), nil, nil)}
+	}
+	tail = append(tail, linkBlocks(t.Commit)...)
+
+	blocks := header
+	// Every step takes a section and a context block, the collapsed ones share one section
+	room := maxBlocks - len(header) - len(tail)
+	if 2*len(steps) > room {
+		keep := max((room-1)/2, 0)
+		blocks = append(blocks, collapsedBlock(steps[:len(steps)-keep]))
+		steps = steps[len(steps)-keep:]
+	}
+	for _, step := range steps {
+		blocks = append(blocks, StepBlocks(step, t.Updated)...)
+	}
+	blocks = append(blocks, tail...)
+	if len(blocks) > maxBlocks {
+		// A matrix too large for the grid, render the plain text instead
+		return []slack.Block{slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, sectionText(notifier.FormatTimeline(t)), false, false), nil, nil)}
+	}
+	return blocks
+}
+
+// collapsedBlock renders the oldest steps of a long timeline as one section, a line per step
+func collapsedBlock(steps []notifier.Step) slack.Block {
+	text := fmt.Sprintf("🗂️ *%d earlier steps*", len(steps))
+	for _, step := range steps {
+		title := fmt.Sprintf("*%s*", step.Text)
+		if step.Status != "" {
+			title = step.Title()
+		}
+		text += fmt.Sprintf("\n%s · 🕗 %s", title, step.Time.Format(time.DateTime))
+	}
+	return slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, sectionText(text), false, false), nil, nil)
+}
+
+// sectionText caps text at maxSectionText, whole lines are kept when possible and a code block
+// left open by the cut is closed again
+func sectionText(text string) string {
+	if len(text) <= maxSectionText {
+		return text
+	}
+	const more, fence = "\n…", "\n```"
+	cut := text[:maxSectionText-len(more)-len(fence)]
+	if i := strings.LastIndex(cut, "\n"); i > 0 {
+		cut = cut[:i]
+	}
+	for !utf8.ValidString(cut) {
+		cut = cut[:len(cut)-1]
+	}
+	cut += more
+	if strings.Count(cut, "```")%2 == 1 {
+		cut += fence
+	}
+	return cut
+}
+
+// maxSectionFields is the number of fields Slack allows in a section block
+const maxSectionFields = 10
+
+// MatrixBlocks renders the matrix status and a two column grid of the legs, each leg links to its job
+func MatrixBlocks(t *notifier.Timeline) []slack.Block {
+	blocks := []slack.Block{
+		slack.NewDividerBlock(),
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, notifier.FormatMatrixStatus(t), false, false), nil, nil),
+	}
+	var fields []*slack.TextBlockObject
+	for _, leg := range t.Legs() {
+		name := fmt.Sprintf("*%s*", leg.Name)
+		if leg.URL != "" {
+			name = fmt.Sprintf("<%s|%s>", leg.URL, leg.Name)
+		}
+		field := fmt.Sprintf("%s %s · %s", leg.Status.Emoji(), name, notifier.FormatLegDuration(leg, t.Updated))
+		if leg.Text != "" {
+			field += "\n" + leg.Text
+		}
+		fields = append(fields, slack.NewTextBlockObject(slack.MarkdownType, field, false, false))
+		if len(fields) == maxSectionFields {
+			blocks = append(blocks, slack.NewSectionBlock(nil, fields, nil))
+			fields = nil
+		}
+	}
+	if len(fields) > 0 {
+		blocks = append(blocks, slack.NewSectionBlock(nil, fields, nil))
+	}
+	return blocks
+}
+
+// AppendUpdateBlocks adds the blocks of a new message line, keeping the link buttons last
+func AppendUpdateBlocks(blocks, update []slack.Block) []slack.Block {
+	var actions []slack.Block
+	result := make([]slack.Block, 0, len(blocks)+len(update))
+	for _, b := range blocks {
+		if b.BlockType() == slack.MBTAction {
+			actions = append(actions, b)
+			continue
+		}
+		result = append(result, b)
+	}
+	result = append(result, update...)
+	return append(result, actions...)
+}
+
+func commitFields(info *notifier.CommitInfo) []*slack.TextBlockObject {
+	var fields []*slack.TextBlockObject
+	add := func(label, value string) {
+		if value != "" {
+			fields = append(fields, slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("%s\n%s", label, value), false, false))
+		}
+	}
+	add("📌 *Commit:*", wrapCode(info.CommitSha))
+	add("🔖 *Branch:*", wrapCode(info.Branch))
+	add("🛠️ *Workflow:*", wrapCode(info.WorkflowName))
+	add("👤 *Author:*", info.Author)
+	add("🐳 *Image Tag:*", info.ImageTag)
+	add("🕗 *Commit Time:*", info.CommitTime)
+	if info.Pusher != info.Author {
+		add("🚀 *Triggered by:*", info.Pusher)
+	}
+	if info.PRNumber != "" {
+		add("🔃 *Pull Request:*", fmt.Sprintf("#%s %s", info.PRNumber, info.PRTitle))
+	}
+	add("🏷️ *Release:*", info.ReleaseTag)
+	return fields
+}
+
+func linkButtons(info *notifier.CommitInfo) []slack.BlockElement {
+	var buttons []slack.BlockElement
+	if info.RunURL != "" {
+		buttons = append(buttons, slack.NewButtonBlockElement("view_run", "run",
+			slack.NewTextBlockObject(slack.PlainTextType, "View Run", false, false)).WithURL(info.RunURL))
+	}
+	if info.CommitURL != "" {
+		buttons = append(buttons, slack.NewButtonBlockElement("view_commit", "commit",
+			slack.NewTextBlockObject(slack.PlainTextType, "View Commit", false, false)).WithURL(info.CommitURL))
+	}
+	if info.PRURL != "" {
+		buttons = append(buttons, slack.NewButtonBlockElement("view_pr", "pr",
+			slack.NewTextBlockObject(slack.PlainTextType, "View Pull Request", false, false)).WithURL(info.PRURL))
+	}
+	if info.ReleaseURL != "" {
+		buttons = append(buttons, slack.NewButtonBlockElement("view_release", "release",
+			slack.NewTextBlockObject(slack.PlainTextType, "View Release", false, false)).WithURL(info.ReleaseURL))
+	}
+	if info.CompareURL != "" {
+		buttons = append(buttons, slack.NewButtonBlockElement("view_compare", "compare",
+			slack.NewTextBlockObject(slack.PlainTextType, "View Changes", false, false)).WithURL(info.CompareURL))
+	}
+	return buttons
+}
+
+func wrapCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return fmt.Sprintf("`%s`", s)
+}
+
+// Post sends a rendered payload with its blocks and metadata, if any
+func (c *SlackClient) Post(ctx context.Context, payload MessagePayload) (string, string, error) {
+	opts := payload.contentOptions()
+	if payload.ThreadTS != "" {
+		opts = append(opts, slack.MsgOptionTS(payload.ThreadTS))
+	}
+	if payload.ReplyBroadcast {
+		opts = append(opts, slack.MsgOptionBroadcast())
+	}
+	var chId, ts string
+	err := c.retry.Do(ctx, false, func() error {
+		var err error
+		chId, ts, err = c.PostMessageContext(ctx, payload.Channel, opts...)
+		return retryable(err)
+	})
+	if err != nil {
+		slog.Error("Failed to Post Slack Message", slog.String("error", err.Error()))
+		return "", "", fmt.Errorf("failed to Post Slack Message- %w", err)
+	}
+	return chId, ts, nil
+}
+
+// Edit replaces the text, blocks and metadata of the message ts in place with chat.update
+func (c *SlackClient) Edit(ctx context.Context, ts string, payload MessagePayload) error {
+	err := c.retry.Do(ctx, true, func() error {
+		_, _, _, err := c.UpdateMessageContext(ctx, payload.Channel, ts, payload.contentOptions()...)
+		return retryable(err)
+	})
+	if err != nil {
+		slog.Error("Failed to Update Slack Message", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to Update Slack Message- %w", err)
+	}
+	return nil
+}
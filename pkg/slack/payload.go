@@ -0,0 +1,115 @@
+package slack
+
+import (
+	"cicd-notifier/pkg/notifier"
+	"fmt"
+
+	"github.com/slack-go/slack"
+)
+
+// timelineEventType identifies the message metadata the timeline is stored in
+const timelineEventType = "cicd_notifier_timeline"
+
+// stepEventType identifies the metadata of the thread replies updates are recorded in
+const stepEventType = "cicd_notifier_step"
+
+// MessagePayload is the chat.postMessage request a message is posted with
+type MessagePayload struct {
+	Channel        string               `json:"channel"`
+	Text           string               `json:"text"`
+	Blocks         []slack.Block        `json:"blocks,omitempty"`
+	Metadata       *slack.SlackMetadata `json:"metadata,omitempty"`
+	ThreadTS       string               `json:"thread_ts,omitempty"`
+	ReplyBroadcast bool                 `json:"reply_broadcast,omitempty"`
+}
+
+// contentOptions returns the text, blocks and metadata of the payload as message options
+func (payload MessagePayload) contentOptions() []slack.MsgOption {
+	opts := []slack.MsgOption{slack.MsgOptionText(payload.Text, false)}
+	if len(payload.Blocks) > 0 {
+		opts = append(opts, slack.MsgOptionBlocks(payload.Blocks...))
+	}
+	if payload.Metadata != nil {
+		opts = append(opts, slack.MsgOptionMetadata(*payload.Metadata))
+	}
+	return opts
+}
+
+// RenderMessage returns the payload Send posts for msg, blocks selects Block Kit rendering
+func RenderMessage(channelID string, msg notifier.Message, blocks bool) (MessagePayload, error) {
+	return RenderTimeline(channelID, notifier.NewTimeline(msg), blocks)
+}
+
+// RenderTimeline renders the whole timeline and stores it in the message metadata
+func RenderTimeline(channelID string, t *notifier.Timeline, blocks bool) (MessagePayload, error) {
+	eventPayload, err := t.MarshalPayload()
+	if err != nil {
+		return MessagePayload{}, fmt.Errorf("failed to encode slack metadata- %w", err)
+	}
+	payload := MessagePayload{
+		Channel:  channelID,
+		Text:     notifier.FormatTimeline(t),
+		Metadata: &slack.SlackMetadata{EventType: timelineEventType, EventPayload: eventPayload},
+	}
+	if blocks {
+		payload.Blocks = TimelineBlocks(t)
+	}
+	return payload, nil
+}
+
+// RenderUpdate returns the text and blocks Update appends to the referenced message
+func RenderUpdate(ref notifier.Ref, msg notifier.Message, blocks bool) MessagePayload {
+	payload := MessagePayload{Channel: ref.ChannelID, Text: notifier.FormatUpdate(msg)}
+	if blocks {
+		payload.Blocks = StepBlocks(notifier.NewTimeline(msg).Steps[0], msg.Time)
+	}
+	return payload
+}
+
+// RenderReply returns the payload Reply posts in the thread of the referenced message
+func RenderReply(ref notifier.Ref, msg notifier.Message, blocks, broadcast bool) MessagePayload {
+	payload := RenderUpdate(ref, msg, blocks)
+	payload.ThreadTS = ref.MessageID
+	payload.ReplyBroadcast = broadcast
+	return payload
+}
+
+// RenderStep returns the thread reply Update records msg in, the message carries it in its metadata
+func RenderStep(ref notifier.Ref, msg notifier.Message, blocks bool) (MessagePayload, error) {
+	eventPayload, err := msg.MarshalPayload()
+	if err != nil {
+		return MessagePayload{}, fmt.Errorf("failed to encode slack metadata- %w", err)
+	}
+	payload := RenderUpdate(ref, msg, blocks)
+	payload.ThreadTS = ref.MessageID
+	payload.Metadata = &slack.SlackMetadata{EventType: stepEventType, EventPayload: eventPayload}
+	return payload, nil
+}
+
+// keySearchPages bounds how far back Find looks for a key, in pages of 200 messages
+const keySearchPages = 5
+
+// timelineFromMetadata restores the timeline stored with a message, ok is false for
+// messages sent before timelines were stored or by other apps
+func timelineFromMetadata(metadata slack.SlackMetadata) (*notifier.Timeline, bool) {
+	if metadata.EventType != timelineEventType {
+		return nil, false
+	}
+	t, err := notifier.UnmarshalTimeline(metadata.EventPayload)
+	if err != nil {
+		return nil, false
+	}
+	return t, true
+}
+
+// stepFromMetadata restores the update recorded in a thread reply, ok is false for other replies
+func stepFromMetadata(metadata slack.SlackMetadata) (notifier.Message, bool) {
+	if metadata.EventType != stepEventType {
+		return notifier.Message{}, false
+	}
+	msg, err := notifier.UnmarshalMessage(metadata.EventPayload)
+	if err != nil {
+		return notifier.Message{}, false
+	}
+	return msg, true
+}
@@ -0,0 +1,94 @@
+package slack
+
+import (
+	"cicd-notifier/pkg/notifier"
+	"context"
+	"fmt"
+
+	"github.com/slack-go/slack"
+)
+
+// AddPin pins a message, pinning it twice is not an error
+func (c *SlackClient) AddPin(ctx context.Context, chId, msgId string) error {
+	err := c.retry.Do(ctx, true, func() error {
+		return retryable(c.AddPinContext(ctx, chId, slack.NewRefToMessage(chId, msgId)))
+	})
+	if err != nil && !isSlackError(err, "already_pinned") {
+		return fmt.Errorf("failed to pin Slack message- %w", err)
+	}
+	return nil
+}
+
+// RemovePin unpins a message, a message that isn't pinned is not an error
+func (c *SlackClient) RemovePin(ctx context.Context, chId, msgId string) error {
+	err := c.retry.Do(ctx, true, func() error {
+		return retryable(c.RemovePinContext(ctx, chId, slack.NewRefToMessage(chId, msgId)))
+	})
+	if err != nil && !isSlackError(err, "no_pin") {
+		return fmt.Errorf("failed to unpin Slack message- %w", err)
+	}
+	return nil
+}
+
+// Pins lists the items pinned to a channel
+func (c *SlackClient) Pins(ctx context.Context, chId string) ([]slack.Item, error) {
+	var items []slack.Item
+	err := c.retry.Do(ctx, true, func() error {
+		var err error
+		items, _, err = c.ListPinsContext(ctx, chId)
+		return retryable(err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Slack pins- %w", err)
+	}
+	return items, nil
+}
+
+// SetTopic replaces the channel topic
+func (c *SlackClient) SetTopic(ctx context.Context, chId, topic string) error {
+	err := c.retry.Do(ctx, true, func() error {
+		_, err := c.SetTopicOfConversationContext(ctx, chId, topic)
+		return retryable(err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set Slack channel topic- %w", err)
+	}
+	return nil
+}
+
+// Pin pins the referenced message. With exclusive the bot's other pinned messages are unpinned
+// afterwards, pins of users and other apps are left alone.
+func (p *Provider) Pin(ctx context.Context, ref notifier.Ref, exclusive bool) error {
+	if err := p.client.AddPin(ctx, ref.ChannelID, ref.MessageID); err != nil {
+		return err
+	}
+	if !exclusive {
+		return nil
+	}
+	auth, err := p.client.AuthTest(ctx)
+	if err != nil {
+		return err
+	}
+	items, err := p.client.Pins(ctx, ref.ChannelID)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if item.Message == nil || item.Message.BotID == "" || item.Message.BotID != auth.BotID || item.Message.Timestamp == ref.MessageID {
+			continue
+		}
+		if err := p.client.RemovePin(ctx, ref.ChannelID, item.Message.Timestamp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Provider) Unpin(ctx context.Context, ref notifier.Ref) error {
+	return p.client.RemovePin(ctx, ref.ChannelID, ref.MessageID)
+}
+
+// SetTopic sets the channel topic, Slack renders it as plain text
+func (p *Provider) SetTopic(ctx context.Context, channelID, topic string) error {
+	return p.client.SetTopic(ctx, channelID, topic)
+}
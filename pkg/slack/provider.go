@@ -0,0 +1,316 @@
+package slack
+
+import (
+	"cicd-notifier/pkg/notifier"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// Provider implements notifier.Provider for Slack
+type Provider struct {
+	client     *SlackClient
+	blocks     bool
+	retry      notifier.RetryPolicy
+	apiURL     string
+	scopes     *scopeRecorder
+	broadcast  bool
+	concurrent bool
+	merge      notifier.RetryPolicy // backoff between renders when concurrent updates conflict
+}
+
+// ProviderOption configures a Provider
+type ProviderOption func(*Provider)
+
+// WithBlocks renders messages with Block Kit, plain text stays as the notification fallback
+func WithBlocks(enabled bool) ProviderOption {
+	return func(p *Provider) {
+		p.blocks = enabled
+	}
+}
+
+// WithReplyBroadcast also shows thread replies in the channel (reply_broadcast)
+func WithReplyBroadcast(enabled bool) ProviderOption {
+	return func(p *Provider) {
+		p.broadcast = enabled
+	}
+}
+
+// WithConcurrentUpdates records every update in the thread of the message and renders it from all
+// of them, for messages several jobs update at once (upsert, aggregate). See Update.
+func WithConcurrentUpdates(enabled bool) ProviderOption {
+	return func(p *Provider) {
+		p.concurrent = enabled
+	}
+}
+
+// WithRetry retries failed API calls, posting is only retried when Slack rejected it (rate limits)
+func WithRetry(policy notifier.RetryPolicy) ProviderOption {
+	return func(p *Provider) {
+		p.retry = policy
+	}
+}
+
+// WithAPIURL points the client at another Slack API base URL, e.g. a local test server
+func WithAPIURL(url string) ProviderOption {
+	return func(p *Provider) {
+		p.apiURL = url
+	}
+}
+
+// NewProvider creates a Slack provider with the given bot token
+func NewProvider(token string, opts ...ProviderOption) (*Provider, error) {
+	p := &Provider{merge: mergeBackoff}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.scopes = &scopeRecorder{client: &http.Client{}}
+	clientOpts := []slack.Option{slack.OptionHTTPClient(p.scopes)}
+	if p.apiURL != "" {
+		clientOpts = append(clientOpts, slack.OptionAPIURL(p.apiURL))
+	}
+	client, err := NewClient(token, clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+	client.SetRetryPolicy(p.retry)
+	p.client = client
+	return p, nil
+}
+
+func (p *Provider) Send(ctx context.Context, channelID string, msg notifier.Message) (notifier.Ref, error) {
+	payload, err := RenderMessage(channelID, msg, p.blocks)
+	if err != nil {
+		return notifier.Ref{}, err
+	}
+	chId, ts, err := p.client.Post(ctx, payload)
+	return notifier.Ref{ChannelID: chId, MessageID: ts}, err
+}
+
+// mergeAttempts bounds how often Update renders the message again while other jobs keep recording updates
+const mergeAttempts = 8
+
+// mergeBackoff spreads out jobs whose renders conflicted, independent of the API retry policy so
+// all attempts stay well within the run timeout
+var mergeBackoff = notifier.RetryPolicy{BaseDelay: 250 * time.Millisecond, MaxDelay: 2 * time.Second}
+
+// Update adds msg to the timeline stored in the message metadata and edits the message in place,
+// so its ts stays valid for every job updating it. With WithConcurrentUpdates, or when other jobs
+// already recorded updates in the thread, msg is recorded there as well, see recordUpdate.
+// Messages without a timeline get the line appended.
+func (p *Provider) Update(ctx context.Context, ref notifier.Ref, msg notifier.Message) (notifier.Ref, error) {
+	existing, err := p.client.getMessage(ctx, ref.ChannelID, ref.MessageID)
+	if err != nil {
+		return notifier.Ref{}, err
+	}
+	t, ok := timelineFromMetadata(existing.Metadata)
+	if !ok {
+		return ref, p.appendLine(ctx, ref, existing, msg)
+	}
+	if p.concurrent {
+		return p.recordUpdate(ctx, ref, existing, msg)
+	}
+	// Re-rendering from the metadata alone would drop the updates recorded in the thread
+	if existing.ReplyCount > 0 {
+		steps, _, err := p.recordedSteps(ctx, ref)
+		if err != nil {
+			return notifier.Ref{}, err
+		}
+		if len(steps) > 0 {
+			return p.recordUpdate(ctx, ref, existing, msg)
+		}
+	}
+	t.Add(msg)
+	payload, err := RenderTimeline(ref.ChannelID, t, p.blocks)
+	if err != nil {
+		return notifier.Ref{}, err
+	}
+	if err := p.client.Edit(ctx, ref.MessageID, payload); err != nil {
+		return notifier.Ref{}, err
+	}
+	return ref, nil
+}
+
+// recordUpdate records msg in a thread reply and re-renders the message from the timeline it was
+// sent with plus every update recorded in the thread. Slack has no compare-and-swap: a job that read
+// the thread before a parallel job (a build matrix) recorded its update can overwrite the message
+// without it. recordUpdate therefore reads the thread again after editing and renders once more when
+// it changed, whoever edits last has seen every update.
+func (p *Provider) recordUpdate(ctx context.Context, ref notifier.Ref, existing slack.Message, msg notifier.Message) (notifier.Ref, error) {
+	record, err := RenderStep(ref, msg, p.blocks)
+	if err != nil {
+		return notifier.Ref{}, err
+	}
+	if _, _, err := p.client.Post(ctx, record); err != nil {
+		return notifier.Ref{}, err
+	}
+	var rendered []string
+	for writes := 0; ; writes++ {
+		steps, ids, err := p.recordedSteps(ctx, ref)
+		if err != nil {
+			return notifier.Ref{}, err
+		}
+		if writes > 0 {
+			if slices.Equal(ids, rendered) {
+				return ref, nil
+			}
+			// The update is recorded, the job that keeps changing the thread renders it
+			if writes == mergeAttempts {
+				slog.Warn("Slack message kept changing, leaving the render to the next update", slog.Int("attempts", writes))
+				return ref, nil
+			}
+			// Back off on conflicts only, the backoff grows with every one so colliding jobs spread out
+			slog.Info("Slack message was updated concurrently, rendering it again", slog.Int("updates", len(ids)))
+			if err := p.merge.Wait(ctx, writes); err != nil {
+				return notifier.Ref{}, err
+			}
+			if steps, ids, err = p.recordedSteps(ctx, ref); err != nil {
+				return notifier.Ref{}, err
+			}
+		}
+		t, _ := timelineFromMetadata(existing.Metadata)
+		for _, step := range steps {
+			t.Add(step)
+		}
+		payload, err := RenderTimeline(ref.ChannelID, t, p.blocks)
+		if err != nil {
+			return notifier.Ref{}, err
+		}
+		// The metadata keeps the timeline as sent, the updates stay in the thread
+		payload.Metadata = &existing.Metadata
+		if err := p.client.Edit(ctx, ref.MessageID, payload); err != nil {
+			return notifier.Ref{}, err
+		}
+		rendered = ids
+	}
+}
+
+// recordedSteps reads the updates recorded in the thread of the message oldest first, with the ts of their replies
+func (p *Provider) recordedSteps(ctx context.Context, ref notifier.Ref) ([]notifier.Message, []string, error) {
+	replies, err := p.client.Replies(ctx, ref.ChannelID, ref.MessageID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var steps []notifier.Message
+	var ids []string
+	for _, reply := range replies {
+		if step, ok := stepFromMetadata(reply.Metadata); ok {
+			steps = append(steps, step)
+			ids = append(ids, reply.Timestamp)
+		}
+	}
+	return steps, ids, nil
+}
+
+// appendLine appends msg to the text of a message sent before timelines were stored
+func (p *Provider) appendLine(ctx context.Context, ref notifier.Ref, existing slack.Message, msg notifier.Message) error {
+	slog.Warn("Slack message has no timeline metadata, appending to its text")
+	payload := RenderUpdate(ref, msg, p.blocks)
+	payload.Text = existing.Text + payload.Text
+	if p.blocks {
+		payload.Blocks = AppendUpdateBlocks(existing.Blocks.BlockSet, payload.Blocks)
+	}
+	return p.client.Edit(ctx, ref.MessageID, payload)
+}
+
+// Reply posts msg in the thread of the referenced message
+func (p *Provider) Reply(ctx context.Context, ref notifier.Ref, msg notifier.Message) (notifier.Ref, error) {
+	chId, ts, err := p.client.Post(ctx, RenderReply(ref, msg, p.blocks, p.broadcast))
+	return notifier.Ref{ChannelID: chId, MessageID: ts}, err
+}
+
+// Delete deletes the message together with the updates recorded in its thread
+func (p *Provider) Delete(ctx context.Context, ref notifier.Ref) error {
+	if err := p.deleteRecords(ctx, ref); err != nil {
+		return err
+	}
+	return p.client.Delete(ctx, ref.ChannelID, ref.MessageID)
+}
+
+// deleteRecords deletes the thread replies Update recorded steps in, other replies are left alone
+func (p *Provider) deleteRecords(ctx context.Context, ref notifier.Ref) error {
+	replies, err := p.client.Replies(ctx, ref.ChannelID, ref.MessageID)
+	if err != nil {
+		return err
+	}
+	for _, reply := range replies {
+		if _, ok := stepFromMetadata(reply.Metadata); !ok {
+			continue
+		}
+		if err := p.client.Delete(ctx, ref.ChannelID, reply.Timestamp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Cleanup deletes the messages the bot posted in channelID before the cutoff, page by page while
+// walking the history back from the cutoff, messages of other bots and users are left alone
+func (p *Provider) Cleanup(ctx context.Context, channelID string, before time.Time) (int, error) {
+	auth, err := p.client.AuthTest(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if auth.BotID == "" {
+		return 0, errors.New("cleanup needs a bot token, the messages are matched by bot_id")
+	}
+	params := &slack.GetConversationHistoryParameters{
+		ChannelID: channelID,
+		Latest:    timestamp(before),
+		Limit:     200,
+	}
+	deleted := 0
+	var deleteErr error
+	err = p.client.eachHistoryPage(ctx, params, func(messages []slack.Message) bool {
+		for _, msg := range messages {
+			if msg.BotID != auth.BotID {
+				continue
+			}
+			ref := notifier.Ref{ChannelID: channelID, MessageID: msg.Timestamp}
+			if msg.ReplyCount > 0 {
+				if deleteErr = p.deleteRecords(ctx, ref); deleteErr != nil {
+					return false
+				}
+			}
+			if deleteErr = p.client.Delete(ctx, channelID, msg.Timestamp); deleteErr != nil {
+				return false
+			}
+			deleted++
+		}
+		return true
+	})
+	if err != nil {
+		return deleted, err
+	}
+	return deleted, deleteErr
+}
+
+// Find looks for the oldest message whose timeline metadata has key in the recent channel history
+func (p *Provider) Find(ctx context.Context, channelID, key string) (notifier.Ref, bool, error) {
+	params := &slack.GetConversationHistoryParameters{
+		ChannelID:          channelID,
+		Limit:              200,
+		IncludeAllMetadata: true,
+	}
+	var found notifier.Ref
+	pages := 0
+	// The history is newest first, the last match is the oldest message
+	err := p.client.eachHistoryPage(ctx, params, func(messages []slack.Message) bool {
+		for _, msg := range messages {
+			if t, ok := timelineFromMetadata(msg.Metadata); ok && t.Key == key {
+				found = notifier.Ref{ChannelID: channelID, MessageID: msg.Timestamp}
+			}
+		}
+		pages++
+		return pages < keySearchPages
+	})
+	if err != nil {
+		return notifier.Ref{}, false, err
+	}
+	return found, found != notifier.Ref{}, nil
+}
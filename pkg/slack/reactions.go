@@ -0,0 +1,55 @@
+package slack
+
+import (
+	"cicd-notifier/pkg/notifier"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// AddReaction adds the emoji name to a message, a reaction the bot already added is not an error
+func (c *SlackClient) AddReaction(ctx context.Context, chId, msgId, name string) error {
+	err := c.retry.Do(ctx, true, func() error {
+		return retryable(c.AddReactionContext(ctx, name, slack.NewRefToMessage(chId, msgId)))
+	})
+	if err != nil && !isSlackError(err, "already_reacted") {
+		return fmt.Errorf("failed to add :%s: reaction- %w", name, err)
+	}
+	return nil
+}
+
+// RemoveReaction removes the bot's emoji name from a message, a missing reaction is not an error
+func (c *SlackClient) RemoveReaction(ctx context.Context, chId, msgId, name string) error {
+	err := c.retry.Do(ctx, true, func() error {
+		return retryable(c.RemoveReactionContext(ctx, name, slack.NewRefToMessage(chId, msgId)))
+	})
+	if err != nil && !isSlackError(err, "no_reaction") {
+		return fmt.Errorf("failed to remove :%s: reaction- %w", name, err)
+	}
+	return nil
+}
+
+// isSlackError reports whether the API answered with the error code
+func isSlackError(err error, code string) bool {
+	var apiErr slack.SlackErrorResponse
+	return errors.As(err, &apiErr) && apiErr.Err == code
+}
+
+// React removes and then adds the bot's reactions, so a reaction can be swapped in one call.
+// Emoji are Slack names, with or without colons (":white_check_mark:").
+func (p *Provider) React(ctx context.Context, ref notifier.Ref, add, remove []string) error {
+	for _, name := range remove {
+		if err := p.client.RemoveReaction(ctx, ref.ChannelID, ref.MessageID, strings.Trim(name, ":")); err != nil {
+			return err
+		}
+	}
+	for _, name := range add {
+		if err := p.client.AddReaction(ctx, ref.ChannelID, ref.MessageID, strings.Trim(name, ":")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
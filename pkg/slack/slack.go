@@ -1,9 +1,12 @@
 package slack
 
 import (
+	"cicd-notifier/pkg/notifier"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/slack-go/slack"
 )
@@ -11,11 +14,12 @@ import (
 // Client wraps the slack client
 type SlackClient struct {
 	*slack.Client
+	retry notifier.RetryPolicy
 }
 
 // NewClient creates a new Slack client with the given token
-func NewClient(token string) (*SlackClient, error) {
-	client := slack.New(token)
+func NewClient(token string, opts ...slack.Option) (*SlackClient, error) {
+	client := slack.New(token, opts...)
 	return &SlackClient{Client: client}, nil
 }
 
@@ -24,76 +28,133 @@ func InitClient(token string) (*SlackClient, error) {
 	return NewClient(token)
 }
 
-func (c *SlackClient) Send(slackChannel, msg string) (string, string, error) {
-	chId, ts, err := c.PostMessageContext(context.Background(), slackChannel, slack.MsgOptionText(msg, false))
-	if err != nil {
-		slog.Error("Failed to Post Slack Message", slog.String("error", err.Error()))
-		return "", "", fmt.Errorf("failed to Post Slack Message- %s", err.Error())
-	}
-	return chId, ts, nil
+// SetRetryPolicy enables retries of failed API calls
+func (c *SlackClient) SetRetryPolicy(policy notifier.RetryPolicy) {
+	c.retry = policy
 }
-func (c *SlackClient) Update(slackChannel, msgId, newMsg string) (string, string, error) {
-	params := &slack.GetConversationHistoryParameters{
-		ChannelID: slackChannel,
-		Latest:    msgId,
-		Oldest:    msgId,
-		Inclusive: true,
-		Limit:     1,
-	}
-	history, err := c.GetConversationHistory(params)
-	if err != nil {
-		slog.Error("Failed to get slack message", slog.String("error", err.Error()))
-		return "", "", fmt.Errorf("failed to get slack message- %s", err.Error())
+
+// retryable marks rate limits, 5xx responses and network failures as retryable
+// Rate limited requests were rejected before processing, a 5xx may have posted the message anyway
+func retryable(err error) error {
+	if err == nil {
+		return nil
 	}
-	if !(len(history.Messages) > 0) {
-		slog.Error("Couldnt locate slack message")
-		return "", "", fmt.Errorf("couldnt locate slack message")
+	var rateLimited *slack.RateLimitedError
+	if errors.As(err, &rateLimited) {
+		return &notifier.RetryError{Err: err, RetryAfter: rateLimited.RetryAfter, NotProcessed: true}
 	}
-	existingMsg := history.Messages[0].Text
-	if existingMsg == "" {
-		slog.Warn("Found Slack message but its empty")
+	var statusErr slack.StatusCodeError
+	if errors.As(err, &statusErr) && statusErr.Retryable() {
+		return &notifier.RetryError{Err: err}
 	}
-	_, _, err = c.DeleteMessageContext(context.Background(), slackChannel, msgId)
-	if err != nil {
-		slog.Error("Failed to Delete Slack Message", slog.String("error", err.Error()))
-		return "", "", fmt.Errorf("failed To delete Slack Message err= %s", err)
-	}
-	existingMsg += newMsg
-	chId, ts, err := c.Send(slackChannel, existingMsg)
-	if err != nil {
-		return "", "", err
-	}
-	return chId, ts, nil
+	return notifier.NetworkError(err)
 }
 
-func (c *SlackClient) GetMsgContent(chId, msgId string) (string, error) {
+// getMessage reads a single message by its timestamp
+func (c *SlackClient) getMessage(ctx context.Context, chId, msgId string) (slack.Message, error) {
 	params := &slack.GetConversationHistoryParameters{
 		ChannelID: chId,
 		Latest:    msgId,
 		Oldest:    msgId,
 		Inclusive: true,
 		Limit:     1,
+		// The timeline is stored in the message metadata
+		IncludeAllMetadata: true,
 	}
-	history, err := c.GetConversationHistory(params)
+	var history *slack.GetConversationHistoryResponse
+	err := c.retry.Do(ctx, true, func() error {
+		var err error
+		history, err = c.GetConversationHistoryContext(ctx, params)
+		return retryable(err)
+	})
 	if err != nil {
 		slog.Error("Failed to get slack message", slog.String("error", err.Error()))
-		return "", fmt.Errorf("failed to get slack message- %s", err.Error())
+		return slack.Message{}, fmt.Errorf("failed to get slack message- %w", err)
 	}
 	if !(len(history.Messages) > 0) {
 		slog.Error("Couldnt locate slack message")
-		return "", fmt.Errorf("couldnt locate slack message")
+		return slack.Message{}, fmt.Errorf("couldnt locate slack message")
+	}
+	return history.Messages[0], nil
+}
+
+// eachHistoryPage reads the channel history newest first and calls fn with every page until it returns false
+func (c *SlackClient) eachHistoryPage(ctx context.Context, params *slack.GetConversationHistoryParameters, fn func([]slack.Message) bool) error {
+	for {
+		var history *slack.GetConversationHistoryResponse
+		err := c.retry.Do(ctx, true, func() error {
+			var err error
+			history, err = c.GetConversationHistoryContext(ctx, params)
+			return retryable(err)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read slack channel history- %w", err)
+		}
+		if !fn(history.Messages) || !history.HasMore || history.ResponseMetaData.NextCursor == "" {
+			return nil
+		}
+		params.Cursor = history.ResponseMetaData.NextCursor
+	}
+}
+
+// Replies returns the replies in the thread of msgId oldest first, with their metadata
+func (c *SlackClient) Replies(ctx context.Context, chId, msgId string) ([]slack.Message, error) {
+	params := &slack.GetConversationRepliesParameters{
+		ChannelID:          chId,
+		Timestamp:          msgId,
+		Limit:              200,
+		IncludeAllMetadata: true,
 	}
-	existingMsg := history.Messages[0].Text
-	if existingMsg == "" {
-		slog.Warn("Found Slack message but its empty")
+	var replies []slack.Message
+	for {
+		var page []slack.Message
+		var hasMore bool
+		var cursor string
+		err := c.retry.Do(ctx, true, func() error {
+			var err error
+			page, hasMore, cursor, err = c.GetConversationRepliesContext(ctx, params)
+			return retryable(err)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read slack thread- %w", err)
+		}
+		for _, msg := range page {
+			// Every page starts with the parent message
+			if msg.Timestamp != msgId {
+				replies = append(replies, msg)
+			}
+		}
+		if !hasMore || cursor == "" {
+			return replies, nil
+		}
+		params.Cursor = cursor
 	}
-	return existingMsg, nil
 }
-func (c *SlackClient) Delete(chId, msgId string) error {
-	_, _, err := c.DeleteMessageContext(context.Background(), chId, msgId)
+
+// timestamp formats t as a Slack message timestamp
+func timestamp(t time.Time) string {
+	return fmt.Sprintf("%d.%06d", t.Unix(), t.Nanosecond()/int(time.Microsecond))
+}
+
+func (c *SlackClient) Delete(ctx context.Context, chId, msgId string) error {
+	err := c.retry.Do(ctx, true, func() error {
+		_, _, err := c.DeleteMessageContext(ctx, chId, msgId)
+		return retryable(err)
+	})
 	if err != nil {
 		slog.Error("Failed to Delete Slack Message", slog.String("error", err.Error()))
-		return fmt.Errorf("failed To delete Slack Message err= %s", err)
+		return fmt.Errorf("failed To delete Slack Message err= %w", err)
 	}
 	return nil
 }
+
+// AuthTest checks the token, retrying transient failures
+func (c *SlackClient) AuthTest(ctx context.Context) (*slack.AuthTestResponse, error) {
+	var auth *slack.AuthTestResponse
+	err := c.retry.Do(ctx, true, func() error {
+		var err error
+		auth, err = c.AuthTestContext(ctx)
+		return retryable(err)
+	})
+	return auth, err
+}
@@ -0,0 +1,518 @@
+package slack
+
+import (
+	"cicd-notifier/pkg/notifier"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+var testRetry = notifier.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Second}
+
+// newTestProvider serves the Slack API from handler, counting the requests per method, opts override the defaults
+func newTestProvider(t *testing.T, handler func(calls int32, w http.ResponseWriter, r *http.Request), opts ...ProviderOption) (*Provider, map[string]*int32) {
+	t.Helper()
+	calls := map[string]*int32{}
+	for _, method := range []string{"/chat.postMessage", "/conversations.history", "/chat.delete", "/auth.test", "/conversations.info", "/chat.update", "/reactions.add", "/reactions.remove",
+		"/pins.add", "/pins.list", "/pins.remove", "/conversations.setTopic", "/conversations.replies"} {
+		calls[method] = new(int32)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(calls[r.URL.Path], 1)
+		handler(n, w, r)
+	}))
+	t.Cleanup(srv.Close)
+	p, err := NewProvider("xoxb-test", append([]ProviderOption{WithRetry(testRetry), WithAPIURL(srv.URL + "/")}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, calls
+}
+
+// resetConnection closes the connection without a response
+func resetConnection(t *testing.T, w http.ResponseWriter) {
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
+
+func TestSendRetriesRateLimit(t *testing.T) {
+	p, calls := newTestProvider(t, func(n int32, w http.ResponseWriter, r *http.Request) {
+		if n == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"ok":true,"channel":"C1","ts":"1.1"}`))
+	})
+	ref, err := p.Send(context.Background(), "C1", notifier.Message{Text: "hi"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if ref.MessageID != "1.1" {
+		t.Errorf("MessageID = %q, want 1.1", ref.MessageID)
+	}
+	if got := atomic.LoadInt32(calls["/chat.postMessage"]); got != 2 {
+		t.Errorf("postMessage calls = %d, want 2", got)
+	}
+}
+
+func TestSendDoesNotRetryAmbiguousFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(t *testing.T, w http.ResponseWriter)
+	}{
+		{"server error", func(t *testing.T, w http.ResponseWriter) { w.WriteHeader(http.StatusServiceUnavailable) }},
+		{"connection reset", resetConnection},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, calls := newTestProvider(t, func(n int32, w http.ResponseWriter, r *http.Request) {
+				tt.handler(t, w)
+			})
+			if _, err := p.Send(context.Background(), "C1", notifier.Message{Text: "hi"}); err == nil {
+				t.Fatal("Send() error = nil, want error")
+			}
+			// The message may have been posted, retrying could duplicate it
+			if got := atomic.LoadInt32(calls["/chat.postMessage"]); got != 1 {
+				t.Errorf("postMessage calls = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestSendHonoursContext(t *testing.T) {
+	// The hung handler is released before the server shuts down, cleanups run last-in first-out
+	release := make(chan struct{})
+	p, calls := newTestProvider(t, func(n int32, w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+	t.Cleanup(func() { close(release) })
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := p.Send(ctx, "C1", notifier.Message{Text: "hi"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Send() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if got := atomic.LoadInt32(calls["/chat.postMessage"]); got != 1 {
+		t.Errorf("postMessage calls = %d, want 1", got)
+	}
+}
+
+func TestUpdateRetriesReads(t *testing.T) {
+	p, calls := newTestProvider(t, func(n int32, w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/conversations.history":
+			switch n {
+			case 1:
+				resetConnection(t, w)
+			case 2:
+				w.WriteHeader(http.StatusBadGateway)
+			default:
+				w.Write([]byte(`{"ok":true,"messages":[{"type":"message","ts":"1.1","text":"first\n"}]}`))
+			}
+		case "/chat.update":
+			w.Write([]byte(`{"ok":true,"channel":"C1","ts":"1.1"}`))
+		}
+	})
+	ref, err := p.Update(context.Background(), notifier.Ref{ChannelID: "C1", MessageID: "1.1"}, notifier.Message{Text: "second"})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if ref.MessageID != "1.1" {
+		t.Errorf("MessageID = %q, want 1.1", ref.MessageID)
+	}
+	if got := atomic.LoadInt32(calls["/conversations.history"]); got != 3 {
+		t.Errorf("history calls = %d, want 3", got)
+	}
+}
+
+func TestUpdateRerendersTimeline(t *testing.T) {
+	start := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	first := notifier.Message{Text: "Deploy started", Commit: &notifier.CommitInfo{CommitSha: "abc123"}, Time: start}
+	thread := newSlackThread(t, first)
+	// The text was edited by hand, the timeline in the metadata is what gets rendered
+	thread.text = "edited"
+	p, calls := newTestProvider(t, thread.handle)
+
+	second := notifier.Message{Text: "Deployed", Time: start.Add(time.Minute)}
+	if _, err := p.Update(context.Background(), notifier.Ref{ChannelID: "C1", MessageID: "1.1"}, second); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	expected := notifier.FormatText(first) + notifier.FormatLine(second)
+	if thread.text != expected {
+		t.Errorf("edited text = %q, want %q", thread.text, expected)
+	}
+	if got := thread.timeline(t); len(got.Steps) != 2 {
+		t.Errorf("message metadata has %d steps, want 2", len(got.Steps))
+	}
+	// A single writer doesn't touch the thread
+	if len(thread.replies) != 0 || atomic.LoadInt32(calls["/conversations.replies"]) != 0 {
+		t.Errorf("thread replies = %+v, want the thread left alone", thread.replies)
+	}
+	if got := atomic.LoadInt32(calls["/chat.update"]); got != 1 {
+		t.Errorf("update calls = %d, want 1", got)
+	}
+}
+
+func TestTimelineBlocksWithoutCommitInfo(t *testing.T) {
+	timeline := notifier.NewTimeline(notifier.Message{Text: "hi", Time: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)})
+	blocks := TimelineBlocks(timeline)
+	if _, ok := blocks[0].(*slack.HeaderBlock); ok {
+		t.Errorf("blocks[0] = %+v, want no header without commit info", blocks[0])
+	}
+}
+
+func TestTimelineBlocksCollapsesOldSteps(t *testing.T) {
+	start := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	commit := &notifier.CommitInfo{CommitSha: "abc123", RunURL: "https://ci.example/run", CommitMsg: strings.Repeat("long commit message\n", 300)}
+	timeline := notifier.NewTimeline(notifier.Message{Text: "step 0", Commit: commit, Time: start})
+	for i := 1; i < 30; i++ {
+		timeline.Add(notifier.Message{Text: fmt.Sprintf("step %d", i), Time: start.Add(time.Duration(i) * time.Minute)})
+	}
+
+	blocks := TimelineBlocks(timeline)
+	if len(blocks) > maxBlocks {
+		t.Fatalf("TimelineBlocks() = %d blocks, want at most %d", len(blocks), maxBlocks)
+	}
+	raw, err := json.Marshal(blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), "earlier steps") || !strings.Contains(string(raw), "step 29") {
+		t.Errorf("blocks = %s, want the oldest steps collapsed and the newest kept", raw)
+	}
+	for _, block := range blocks {
+		if section, ok := block.(*slack.SectionBlock); ok && section.Text != nil && len(section.Text.Text) > maxSectionText {
+			t.Errorf("section text has %d characters, want at most %d", len(section.Text.Text), maxSectionText)
+		}
+	}
+}
+
+func TestConcurrentUpdateRecordsInThread(t *testing.T) {
+	start := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	first := notifier.Message{Text: "Matrix started", Time: start}
+	thread := newSlackThread(t, first)
+	p, calls := newTestProvider(t, thread.handle, WithConcurrentUpdates(true))
+
+	leg := notifier.Message{Text: "linux passed", Step: "linux", Status: notifier.StatusSuccess, Time: start.Add(time.Minute)}
+	if _, err := p.Update(context.Background(), notifier.Ref{ChannelID: "C1", MessageID: "1.1"}, leg); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if !strings.Contains(thread.text, "linux passed") {
+		t.Errorf("edited text = %q, want the leg rendered", thread.text)
+	}
+	if got := thread.timeline(t); len(got.Steps) != 1 {
+		t.Errorf("message metadata has %d steps, want the timeline as sent", len(got.Steps))
+	}
+	if len(thread.replies) != 1 || thread.replies[0].threadTS != "1.1" {
+		t.Fatalf("thread replies = %+v, want the update recorded in the thread of 1.1", thread.replies)
+	}
+	// Uncontended, the thread is read once to render and once to confirm nothing changed
+	if got := atomic.LoadInt32(calls["/conversations.replies"]); got != 2 {
+		t.Errorf("replies calls = %d, want 2", got)
+	}
+	if got := atomic.LoadInt32(calls["/chat.update"]); got != 1 {
+		t.Errorf("update calls = %d, want 1", got)
+	}
+
+	// A plain update of the same message keeps the recorded leg
+	single, _ := newTestProvider(t, thread.handle)
+	done := notifier.Message{Text: "Released", Time: start.Add(2 * time.Minute)}
+	if _, err := single.Update(context.Background(), notifier.Ref{ChannelID: "C1", MessageID: "1.1"}, done); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if !strings.Contains(thread.text, "linux passed") || !strings.Contains(thread.text, "Released") {
+		t.Errorf("edited text = %q, want the recorded leg and the new line", thread.text)
+	}
+}
+
+func TestConcurrentUpdateGivesUpQuietly(t *testing.T) {
+	thread := newSlackThread(t, notifier.Message{Text: "Matrix started"})
+	p, calls := newTestProvider(t, func(n int32, w http.ResponseWriter, r *http.Request) {
+		// Another job records an update before every read, the thread never settles
+		if r.URL.Path == "/conversations.replies" {
+			thread.mu.Lock()
+			thread.replies = append(thread.replies, slackReply{ts: fmt.Sprintf("3.%06d", n), threadTS: "1.1", metadata: thread.replies[0].metadata})
+			thread.mu.Unlock()
+		}
+		thread.handle(n, w, r)
+	}, WithConcurrentUpdates(true))
+	p.merge = notifier.RetryPolicy{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+	leg := notifier.Message{Step: "linux", Status: notifier.StatusSuccess, Time: time.Now()}
+	if _, err := p.Update(context.Background(), notifier.Ref{ChannelID: "C1", MessageID: "1.1"}, leg); err != nil {
+		t.Fatalf("Update() error = %v, want the recorded update left to the next writer", err)
+	}
+	if got := atomic.LoadInt32(calls["/chat.update"]); got != mergeAttempts {
+		t.Errorf("update calls = %d, want %d", got, mergeAttempts)
+	}
+}
+
+func TestReplyPostsInThread(t *testing.T) {
+	var posted url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		posted = r.PostForm
+		w.Write([]byte(`{"ok":true,"channel":"C1","ts":"2.2"}`))
+	}))
+	defer srv.Close()
+	p, err := NewProvider("xoxb-test", WithAPIURL(srv.URL+"/"), WithReplyBroadcast(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref, err := p.Reply(context.Background(), notifier.Ref{ChannelID: "C1", MessageID: "1.1"}, notifier.Message{Text: "Tests failed"})
+	if err != nil {
+		t.Fatalf("Reply() error = %v", err)
+	}
+	if ref != (notifier.Ref{ChannelID: "C1", MessageID: "2.2"}) {
+		t.Errorf("Reply() ref = %+v", ref)
+	}
+	if posted.Get("thread_ts") != "1.1" || posted.Get("reply_broadcast") != "true" {
+		t.Errorf("posted thread_ts = %q, reply_broadcast = %q", posted.Get("thread_ts"), posted.Get("reply_broadcast"))
+	}
+}
+
+func TestCleanupDeletesOwnOldMessages(t *testing.T) {
+	var requests []string
+	var latest string
+	p, calls := newTestProvider(t, func(n int32, w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.URL.Path {
+		case "/auth.test":
+			w.Write([]byte(`{"ok":true,"user_id":"U1","bot_id":"B1"}`))
+		case "/conversations.history":
+			requests = append(requests, "history")
+			latest = r.PostForm.Get("latest")
+			if r.PostForm.Get("cursor") == "" {
+				w.Write([]byte(`{"ok":true,"has_more":true,"response_metadata":{"next_cursor":"page2"},"messages":[
+					{"type":"message","ts":"1.1","bot_id":"B1"},{"type":"message","ts":"1.2","user":"U2"}]}`))
+				return
+			}
+			w.Write([]byte(`{"ok":true,"messages":[{"type":"message","ts":"1.3","bot_id":"B2"},{"type":"message","ts":"1.4","bot_id":"B1"}]}`))
+		case "/chat.delete":
+			requests = append(requests, "delete:"+r.PostForm.Get("ts"))
+			w.Write([]byte(`{"ok":true,"channel":"C1"}`))
+		}
+	})
+
+	count, err := p.Cleanup(context.Background(), "C1", time.Unix(1700000000, 500000000))
+	if err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	// Each page is deleted before the next one is read
+	if count != 2 || fmt.Sprint(requests) != "[history delete:1.1 history delete:1.4]" {
+		t.Errorf("Cleanup() = %d, requests %v, want 2 [history delete:1.1 history delete:1.4]", count, requests)
+	}
+	if latest != "1700000000.500000" {
+		t.Errorf("history latest = %q", latest)
+	}
+	if got := atomic.LoadInt32(calls["/conversations.history"]); got != 2 {
+		t.Errorf("conversations.history calls = %d, want 2", got)
+	}
+}
+
+func TestReactSwapsReactions(t *testing.T) {
+	var requests []string
+	p, _ := newTestProvider(t, func(n int32, w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		requests = append(requests, r.URL.Path+" "+r.PostForm.Get("name"))
+		if r.URL.Path == "/reactions.remove" {
+			// Another job already removed it
+			w.Write([]byte(`{"ok":false,"error":"no_reaction"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	})
+
+	err := p.React(context.Background(), notifier.Ref{ChannelID: "C1", MessageID: "1.1"}, []string{":white_check_mark:"}, []string{"hourglass"})
+	if err != nil {
+		t.Fatalf("React() error = %v", err)
+	}
+	if fmt.Sprint(requests) != "[/reactions.remove hourglass /reactions.add white_check_mark]" {
+		t.Errorf("requests = %v", requests)
+	}
+}
+
+func TestPinUnpinsPreviousOwnPin(t *testing.T) {
+	var unpinned []string
+	p, calls := newTestProvider(t, func(n int32, w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.URL.Path {
+		case "/pins.add":
+			w.Write([]byte(`{"ok":false,"error":"already_pinned"}`))
+		case "/auth.test":
+			w.Write([]byte(`{"ok":true,"user_id":"U1","bot_id":"B1"}`))
+		case "/pins.list":
+			w.Write([]byte(`{"ok":true,"items":[
+				{"type":"message","channel":"C1","message":{"type":"message","ts":"2.2","bot_id":"B1"}},
+				{"type":"message","channel":"C1","message":{"type":"message","ts":"1.1","bot_id":"B1"}},
+				{"type":"message","channel":"C1","message":{"type":"message","ts":"0.5","user":"U2"}}]}`))
+		case "/pins.remove":
+			unpinned = append(unpinned, r.PostForm.Get("timestamp"))
+			w.Write([]byte(`{"ok":true}`))
+		}
+	})
+
+	if err := p.Pin(context.Background(), notifier.Ref{ChannelID: "C1", MessageID: "2.2"}, true); err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+	if fmt.Sprint(unpinned) != "[1.1]" {
+		t.Errorf("unpinned = %v, want [1.1]", unpinned)
+	}
+	if got := atomic.LoadInt32(calls["/pins.add"]); got != 1 {
+		t.Errorf("pins.add calls = %d, want 1", got)
+	}
+}
+
+func TestFindLooksUpKeyInMetadata(t *testing.T) {
+	stored, err := RenderMessage("C1", notifier.Message{Text: "Deploy", Key: "repo-42"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := json.Marshal(stored.Metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, calls := newTestProvider(t, func(n int32, w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.PostForm.Get("cursor") {
+		case "":
+			fmt.Fprintf(w, `{"ok":true,"has_more":true,"response_metadata":{"next_cursor":"page2"},"messages":[{"type":"message","ts":"3.3","metadata":%s}]}`, metadata)
+		case "page2":
+			fmt.Fprintf(w, `{"ok":true,"has_more":true,"response_metadata":{"next_cursor":"page3"},"messages":[{"type":"message","ts":"2.2","metadata":%s}]}`, metadata)
+		default:
+			w.Write([]byte(`{"ok":true,"has_more":true,"response_metadata":{"next_cursor":"page3"},"messages":[{"type":"message","ts":"1.1","text":"chatter"}]}`))
+		}
+	})
+
+	// Two jobs sent the message at once, both converge on the oldest one
+	ref, ok, err := p.Find(context.Background(), "C1", "repo-42")
+	if err != nil || !ok || ref != (notifier.Ref{ChannelID: "C1", MessageID: "2.2"}) {
+		t.Errorf("Find() = %+v, %t, %v", ref, ok, err)
+	}
+	if _, ok, _ := p.Find(context.Background(), "C1", "other"); ok {
+		t.Error("Find() of an unknown key found a message")
+	}
+	// Both lookups read the history up to keySearchPages
+	if got := atomic.LoadInt32(calls["/conversations.history"]); got != 2*keySearchPages {
+		t.Errorf("conversations.history calls = %d, want %d", got, 2*keySearchPages)
+	}
+}
+
+// slackThread serves a message with its timeline and the replies posted in its thread
+type slackThread struct {
+	mu       sync.Mutex
+	text     string
+	metadata string
+	replies  []slackReply
+}
+
+type slackReply struct {
+	ts, threadTS, metadata string
+}
+
+func newSlackThread(t *testing.T, first notifier.Message) *slackThread {
+	t.Helper()
+	payload, err := RenderMessage("C1", first, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := json.Marshal(payload.Metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &slackThread{text: payload.Text, metadata: string(metadata)}
+}
+
+func (s *slackThread) handle(n int32, w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.URL.Path {
+	case "/conversations.history":
+		fmt.Fprintf(w, `{"ok":true,"messages":[{"type":"message","ts":"1.1","reply_count":%d,"metadata":%s}]}`, len(s.replies), s.metadata)
+	case "/conversations.replies":
+		messages := []string{fmt.Sprintf(`{"type":"message","ts":"1.1","metadata":%s}`, s.metadata)}
+		for _, reply := range s.replies {
+			messages = append(messages, fmt.Sprintf(`{"type":"message","ts":%q,"thread_ts":%q,"metadata":%s}`, reply.ts, reply.threadTS, reply.metadata))
+		}
+		fmt.Fprintf(w, `{"ok":true,"messages":[%s]}`, strings.Join(messages, ","))
+	case "/chat.postMessage":
+		reply := slackReply{ts: fmt.Sprintf("2.%06d", len(s.replies)+1), threadTS: r.PostForm.Get("thread_ts"), metadata: r.PostForm.Get("metadata")}
+		s.replies = append(s.replies, reply)
+		fmt.Fprintf(w, `{"ok":true,"channel":"C1","ts":%q}`, reply.ts)
+	case "/chat.update":
+		s.text, s.metadata = r.PostForm.Get("text"), r.PostForm.Get("metadata")
+		w.Write([]byte(`{"ok":true,"channel":"C1","ts":"1.1"}`))
+	}
+}
+
+// timeline decodes the timeline stored in the message metadata
+func (s *slackThread) timeline(t *testing.T) notifier.Timeline {
+	t.Helper()
+	var stored struct {
+		EventPayload notifier.Timeline `json:"event_payload"`
+	}
+	if err := json.Unmarshal([]byte(s.metadata), &stored); err != nil {
+		t.Fatal(err)
+	}
+	return stored.EventPayload
+}
+
+func TestConcurrentUpdatesMergeSteps(t *testing.T) {
+	thread := newSlackThread(t, notifier.Message{Text: "Matrix started"})
+	p, _ := newTestProvider(t, func(n int32, w http.ResponseWriter, r *http.Request) {
+		// The first edit was rendered before most legs finished, it lands after all of them
+		if r.URL.Path == "/chat.update" && n == 1 {
+			time.Sleep(50 * time.Millisecond)
+		}
+		thread.handle(n, w, r)
+	}, WithConcurrentUpdates(true))
+	p.merge = notifier.RetryPolicy{BaseDelay: 5 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	const jobs = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, jobs)
+	for i := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Legs finish one after another
+			time.Sleep(time.Duration(i) * time.Millisecond)
+			leg := notifier.Message{Text: fmt.Sprintf("leg-%02d passed", i), Step: fmt.Sprintf("leg-%02d", i), Status: notifier.StatusSuccess, Time: time.Now()}
+			_, err := p.Update(context.Background(), notifier.Ref{ChannelID: "C1", MessageID: "1.1"}, leg)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Update() error = %v", err)
+		}
+	}
+	// Whichever job edited last rendered every leg
+	for i := range jobs {
+		if leg := fmt.Sprintf("leg-%02d passed", i); !strings.Contains(thread.text, leg) {
+			t.Errorf("message text lost %q:\n%s", leg, thread.text)
+		}
+	}
+	if got := len(thread.replies); got != jobs {
+		t.Errorf("thread has %d updates, want %d", got, jobs)
+	}
+}
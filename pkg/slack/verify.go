@@ -0,0 +1,152 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/slack-go/slack"
+)
+
+// scopeUses explains why the notifier needs each scope
+var scopeUses = map[string]string{
+	"chat:write":           "post, update and delete messages",
+	"channels:history":     "read the message to update in public channels",
+	"groups:history":       "read the message to update in private channels",
+	"im:history":           "read the message to update in direct messages",
+	"mpim:history":         "read the message to update in group direct messages",
+	"reactions:write":      "add and remove reactions (react)",
+	"pins:write":           "pin and unpin messages (pin/unpin)",
+	"pins:read":            "list the pins to replace (pin with unpin_previous)",
+	"channels:write.topic": "set the channel topic (set_topic)",
+	"groups:write.topic":   "set the channel topic in private channels (set_topic)",
+}
+
+// optionalScopes are only needed by some actions, missing ones are reported but don't fail verify.
+// The topic scope is added by the channel type, see topicScope.
+var optionalScopes = []string{"reactions:write", "pins:write", "pins:read"}
+
+// scopeRecorder remembers the X-OAuth-Scopes header Slack returns with every Web API response
+type scopeRecorder struct {
+	client *http.Client
+	mu     sync.Mutex
+	scopes string
+	seen   bool
+}
+
+func (r *scopeRecorder) Do(req *http.Request) (*http.Response, error) {
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if scopes, ok := resp.Header["X-Oauth-Scopes"]; ok && len(scopes) > 0 {
+		r.mu.Lock()
+		r.scopes, r.seen = scopes[0], true
+		r.mu.Unlock()
+	}
+	return resp, nil
+}
+
+// granted returns the token scopes seen in the last response, ok is false when Slack didn't send them
+func (r *scopeRecorder) granted() (scopes []string, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.seen {
+		return nil, false
+	}
+	for _, scope := range strings.Split(r.scopes, ",") {
+		scopes = append(scopes, strings.TrimSpace(scope))
+	}
+	return scopes, true
+}
+
+// ConversationInfo returns the channel details, retrying transient failures
+func (c *SlackClient) ConversationInfo(ctx context.Context, chId string) (*slack.Channel, error) {
+	var channel *slack.Channel
+	err := c.retry.Do(ctx, true, func() error {
+		var err error
+		channel, err = c.GetConversationInfoContext(ctx, &slack.GetConversationInfoInput{ChannelID: chId})
+		return retryable(err)
+	})
+	return channel, err
+}
+
+// historyScope returns the scope conversations.history needs for the channel type
+func historyScope(channel *slack.Channel) string {
+	switch {
+	case channel.IsIM:
+		return "im:history"
+	case channel.IsMpIM:
+		return "mpim:history"
+	case channel.IsPrivate:
+		return "groups:history"
+	}
+	return "channels:history"
+}
+
+// topicScope returns the scope conversations.setTopic needs for the channel type
+func topicScope(channel *slack.Channel) string {
+	if channel.IsPrivate {
+		return "groups:write.topic"
+	}
+	return "channels:write.topic"
+}
+
+// Verify checks the token with auth.test, then that channelID exists, the bot can post there
+// and the token has the scopes send/update need. All problems are reported at once.
+func (p *Provider) Verify(ctx context.Context, channelID string) (string, error) {
+	auth, err := p.client.AuthTest(ctx)
+	if err != nil {
+		return "", fmt.Errorf("slack credentials are invalid- %w", err)
+	}
+	report := []string{fmt.Sprintf("Slack credentials OK: %s in %s", auth.User, auth.Team)}
+	required := []string{"chat:write"}
+	optional := optionalScopes
+	granted, scopesKnown := p.scopes.granted()
+	var problems []string
+
+	if channelID != "" {
+		channel, err := p.client.ConversationInfo(ctx, channelID)
+		switch {
+		case err != nil && strings.Contains(err.Error(), "missing_scope"):
+			problems = append(problems, fmt.Sprintf("cannot read channel %s- conversations.info needs channels:read (groups:read for private channels)", channelID))
+		case err != nil && strings.HasPrefix(channelID, "#"):
+			problems = append(problems, fmt.Sprintf("cannot read channel %s- %s, use the channel ID (C...) instead of its name", channelID, err.Error()))
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("cannot read channel %s- %s", channelID, err.Error()))
+		default:
+			report = append(report, fmt.Sprintf("Channel OK: #%s (%s)", channel.Name, channel.ID))
+			required = append(required, historyScope(channel))
+			optional = append(slices.Clone(optional), topicScope(channel))
+			// chat:write.public lets the bot post to public channels it hasn't joined
+			canPostPublic := !channel.IsPrivate && slices.Contains(granted, "chat:write.public")
+			if !channel.IsIM && !channel.IsMember && !canPostPublic {
+				problems = append(problems, fmt.Sprintf("bot is not a member of #%s, invite it with /invite @%s", channel.Name, auth.User))
+			}
+		}
+	}
+
+	if scopesKnown {
+		for _, scope := range required {
+			if !slices.Contains(granted, scope) {
+				problems = append(problems, fmt.Sprintf("missing scope %s, needed to %s", scope, scopeUses[scope]))
+			}
+		}
+		for _, scope := range optional {
+			if !slices.Contains(granted, scope) {
+				report = append(report, fmt.Sprintf("Optional scope %s missing, needed to %s", scope, scopeUses[scope]))
+			}
+		}
+	} else {
+		slog.Warn("Slack didn't return the token scopes, skipping the scope check")
+	}
+
+	if len(problems) > 0 {
+		return "", fmt.Errorf("slack verification failed:\n- %s", strings.Join(problems, "\n- "))
+	}
+	return strings.Join(report, "\n"), nil
+}
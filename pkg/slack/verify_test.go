@@ -0,0 +1,94 @@
+package slack
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name     string
+		scopes   string
+		channel  string
+		expected string
+		problems []string
+	}{
+		{
+			name:     "member with all scopes",
+			scopes:   "chat:write,channels:read,channels:history",
+			channel:  `{"id":"C1","name":"deploys","is_channel":true,"is_member":true}`,
+			expected: "Slack credentials OK: notifier in Acme\nChannel OK: #deploys (C1)",
+		},
+		{
+			name:    "not a member and missing history scope",
+			scopes:  "chat:write,channels:read",
+			channel: `{"id":"C1","name":"deploys","is_channel":true,"is_member":false}`,
+			problems: []string{
+				"bot is not a member of #deploys, invite it with /invite @notifier",
+				"missing scope channels:history, needed to read the message to update in public channels",
+			},
+		},
+		{
+			name:     "chat:write.public posts without joining",
+			scopes:   "chat:write,chat:write.public,channels:read,channels:history",
+			channel:  `{"id":"C1","name":"deploys","is_channel":true,"is_member":false}`,
+			expected: "Channel OK: #deploys (C1)",
+		},
+		{
+			name:    "private channel needs groups:history",
+			scopes:  "chat:write,groups:read,channels:history",
+			channel: `{"id":"G1","name":"ops","is_private":true,"is_member":true}`,
+			problems: []string{
+				"missing scope groups:history, needed to read the message to update in private channels",
+			},
+		},
+		{
+			name:     "optional scopes follow the channel type",
+			scopes:   "chat:write,groups:read,groups:history,pins:write",
+			channel:  `{"id":"G1","name":"ops","is_private":true,"is_member":true}`,
+			expected: "Optional scope pins:read missing, needed to list the pins to replace (pin with unpin_previous)\nOptional scope groups:write.topic missing, needed to set the channel topic in private channels (set_topic)",
+		},
+		{
+			name:     "missing channels:read",
+			scopes:   "chat:write",
+			problems: []string{"cannot read channel C1- conversations.info needs channels:read"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newTestProvider(t, func(n int32, w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-OAuth-Scopes", tt.scopes)
+				switch r.URL.Path {
+				case "/auth.test":
+					w.Write([]byte(`{"ok":true,"user":"notifier","team":"Acme","bot_id":"B1"}`))
+				case "/conversations.info":
+					if tt.channel == "" {
+						w.Write([]byte(`{"ok":false,"error":"missing_scope"}`))
+						return
+					}
+					w.Write([]byte(`{"ok":true,"channel":` + tt.channel + `}`))
+				}
+			})
+			report, err := p.Verify(context.Background(), "C1")
+			if len(tt.problems) == 0 {
+				if err != nil {
+					t.Fatalf("Verify() error = %v", err)
+				}
+				if !strings.Contains(report, tt.expected) {
+					t.Errorf("Verify() = %q, want it to contain %q", report, tt.expected)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Verify() = %q, want problems %v", report, tt.problems)
+			}
+			for _, problem := range tt.problems {
+				if !strings.Contains(err.Error(), problem) {
+					t.Errorf("Verify() error = %q, want it to contain %q", err.Error(), problem)
+				}
+			}
+		})
+	}
+}
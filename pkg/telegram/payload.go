@@ -0,0 +1,35 @@
+package telegram
+
+import (
+	"cicd-notifier/pkg/notifier"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// parseMode is used for every message, the notifier formats with Markdown (v1) markup
+const parseMode = tgbotapi.ModeMarkdown
+
+// MessagePayload is the sendMessage request a message is sent with
+type MessagePayload struct {
+	ChatID           string `json:"chat_id"`
+	MessageID        string `json:"message_id,omitempty"`
+	MessageThreadID  string `json:"message_thread_id,omitempty"`
+	ReplyToMessageID string `json:"reply_to_message_id,omitempty"`
+	Text             string `json:"text"`
+	ParseMode        string `json:"parse_mode"`
+}
+
+// RenderMessage returns the payload Send posts for msg
+func RenderMessage(chatID string, msg notifier.Message) MessagePayload {
+	return MessagePayload{ChatID: chatID, Text: notifier.FormatTimeline(notifier.NewTimeline(msg)), ParseMode: parseMode}
+}
+
+// RenderUpdate returns the line Update adds to the referenced message
+func RenderUpdate(ref notifier.Ref, msg notifier.Message) MessagePayload {
+	return MessagePayload{ChatID: ref.ChannelID, MessageID: ref.MessageID, Text: notifier.FormatUpdate(msg), ParseMode: parseMode}
+}
+
+// RenderReply returns the payload Reply sends as a reply to the referenced message
+func RenderReply(ref notifier.Ref, msg notifier.Message) MessagePayload {
+	return MessagePayload{ChatID: ref.ChannelID, ReplyToMessageID: ref.MessageID, Text: notifier.FormatUpdate(msg), ParseMode: parseMode}
+}
@@ -0,0 +1,123 @@
+package telegram
+
+import (
+	"cicd-notifier/pkg/notifier"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// request calls a method that returns true on success, retrying transient failures
+func (c *TelegramClient) request(ctx context.Context, config tgbotapi.Chattable) error {
+	bot := c.withContext(ctx)
+	return c.retry.Do(ctx, true, func() error {
+		_, err := bot.Request(config)
+		return retryable(err)
+	})
+}
+
+// chatAndMessage parses the chat and message IDs
+func chatAndMessage(telegramChatId, msgId string) (int64, int, error) {
+	intTelegramChatId, err := strconv.ParseInt(telegramChatId, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to parse telegramChatId to int64- %s", err.Error())
+	}
+	intMsgId, err := strconv.Atoi(msgId)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to parse msgId to int- %s", err.Error())
+	}
+	return intTelegramChatId, intMsgId, nil
+}
+
+// PinMessage pins a message without notifying the chat members
+func (c *TelegramClient) PinMessage(ctx context.Context, telegramChatId, msgId string) error {
+	chatId, intMsgId, err := chatAndMessage(telegramChatId, msgId)
+	if err != nil {
+		return err
+	}
+	config := tgbotapi.PinChatMessageConfig{ChatID: chatId, MessageID: intMsgId, DisableNotification: true}
+	if err := c.request(ctx, config); err != nil {
+		return fmt.Errorf("failed To pin Telegram Message err= %w", err)
+	}
+	return nil
+}
+
+// UnpinMessage unpins a message
+func (c *TelegramClient) UnpinMessage(ctx context.Context, telegramChatId, msgId string) error {
+	chatId, intMsgId, err := chatAndMessage(telegramChatId, msgId)
+	if err != nil {
+		return err
+	}
+	if err := c.request(ctx, tgbotapi.UnpinChatMessageConfig{ChatID: chatId, MessageID: intMsgId}); err != nil {
+		return fmt.Errorf("failed To unpin Telegram Message err= %w", err)
+	}
+	return nil
+}
+
+// SetDescription replaces the chat description, setting the current one again is not an error
+func (c *TelegramClient) SetDescription(ctx context.Context, telegramChatId, description string) error {
+	chatId, err := strconv.ParseInt(telegramChatId, 10, 64)
+	if err != nil {
+		return fmt.Errorf("failed to parse telegramChatId to int64- %s", err.Error())
+	}
+	err = c.request(ctx, tgbotapi.SetChatDescriptionConfig{ChatID: chatId, Description: description})
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) && strings.Contains(apiErr.Message, "not modified") {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed To set Telegram chat description err= %w", err)
+	}
+	return nil
+}
+
+// Pin pins the referenced message. With exclusive the message pinned before it is unpinned
+// when the bot sent it, getChat only returns the most recent pin.
+func (p *Provider) Pin(ctx context.Context, ref notifier.Ref, exclusive bool) error {
+	var previous *tgbotapi.Message
+	if exclusive {
+		chat, err := p.client.Chat(ctx, ref.ChannelID)
+		if err != nil {
+			return err
+		}
+		previous = chat.PinnedMessage
+	}
+	if err := p.client.PinMessage(ctx, ref.ChannelID, ref.MessageID); err != nil {
+		return err
+	}
+	if previous == nil || strconv.Itoa(previous.MessageID) == ref.MessageID {
+		return nil
+	}
+	previousRef := notifier.Ref{ChannelID: ref.ChannelID, MessageID: strconv.Itoa(previous.MessageID)}
+	own, err := p.sentByBot(previous, previousRef)
+	if err != nil || !own {
+		return err
+	}
+	return p.client.UnpinMessage(ctx, previousRef.ChannelID, previousRef.MessageID)
+}
+
+// sentByBot reports whether the bot sent msg. Channel posts have no sender,
+// they are recognised by their timeline in the state file.
+func (p *Provider) sentByBot(msg *tgbotapi.Message, ref notifier.Ref) (bool, error) {
+	if msg.From != nil {
+		return msg.From.ID == p.client.Self.ID, nil
+	}
+	if p.store == nil {
+		return false, nil
+	}
+	t, err := p.store.Load(ref)
+	return t != nil, err
+}
+
+func (p *Provider) Unpin(ctx context.Context, ref notifier.Ref) error {
+	return p.client.UnpinMessage(ctx, ref.ChannelID, ref.MessageID)
+}
+
+// SetTopic sets the chat description, Telegram has no channel topic
+func (p *Provider) SetTopic(ctx context.Context, chatID, topic string) error {
+	return p.client.SetDescription(ctx, chatID, topic)
+}
@@ -0,0 +1,176 @@
+package telegram
+
+import (
+	"cicd-notifier/pkg/notifier"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Provider implements notifier.Provider for Telegram
+type Provider struct {
+	client *TelegramClient
+	store  *notifier.FileStore // nil when no state file is configured, Update is unsupported then
+	topic  string
+}
+
+type providerConfig struct {
+	apiEndpoint string
+	retry       notifier.RetryPolicy
+	stateFile   string
+	topicID     string
+}
+
+// ProviderOption configures a Provider
+type ProviderOption func(*providerConfig)
+
+// WithRetry retries failed API calls, sending is only retried when Telegram rejected it (flood control)
+func WithRetry(policy notifier.RetryPolicy) ProviderOption {
+	return func(c *providerConfig) {
+		c.retry = policy
+	}
+}
+
+// WithAPIEndpoint points the client at another Bot API server ("https://host/bot%s/%s")
+func WithAPIEndpoint(endpoint string) ProviderOption {
+	return func(c *providerConfig) {
+		c.apiEndpoint = endpoint
+	}
+}
+
+// WithStateFile keeps message timelines in path so Update can re-render them,
+// Telegram can't read sent messages back
+func WithStateFile(path string) ProviderOption {
+	return func(c *providerConfig) {
+		c.stateFile = path
+	}
+}
+
+// WithTopic sends messages into a forum topic (message_thread_id)
+func WithTopic(topicID string) ProviderOption {
+	return func(c *providerConfig) {
+		c.topicID = topicID
+	}
+}
+
+// NewProvider creates a Telegram provider, the token is checked with getMe bound to ctx
+func NewProvider(ctx context.Context, token string, opts ...ProviderOption) (*Provider, error) {
+	cfg := providerConfig{apiEndpoint: tgbotapi.APIEndpoint}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	client, err := NewClientWithEndpoint(ctx, token, cfg.apiEndpoint, cfg.retry)
+	if err != nil {
+		return nil, err
+	}
+	p := &Provider{client: client, topic: cfg.topicID}
+	if cfg.stateFile != "" {
+		p.store = &notifier.FileStore{Path: cfg.stateFile}
+	}
+	return p, nil
+}
+
+func (p *Provider) Send(ctx context.Context, channelID string, msg notifier.Message) (notifier.Ref, error) {
+	payload := RenderMessage(channelID, msg)
+	payload.MessageThreadID = p.topic
+	msgId, err := p.client.Post(ctx, payload)
+	if err != nil {
+		return notifier.Ref{}, err
+	}
+	ref := notifier.Ref{ChannelID: channelID, MessageID: msgId}
+	if p.store != nil {
+		// The message was sent, a missing timeline only breaks later updates
+		if err := p.store.Save(ref, notifier.NewTimeline(msg)); err != nil {
+			slog.Warn("Failed to save Telegram timeline", slog.String("error", err.Error()))
+		}
+	}
+	return ref, nil
+}
+
+// Update adds msg to the timeline kept in the state file and re-renders the message in place
+func (p *Provider) Update(ctx context.Context, ref notifier.Ref, msg notifier.Message) (notifier.Ref, error) {
+	if p.store == nil {
+		return notifier.Ref{}, fmt.Errorf("update message in Telegram needs a state file: %w", notifier.ErrUnsupported)
+	}
+	t, err := p.store.Load(ref)
+	if err != nil {
+		return notifier.Ref{}, err
+	}
+	if t == nil {
+		return notifier.Ref{}, fmt.Errorf("no timeline for Telegram message %s in %s, it must be sent with the same state file", ref.MessageID, p.store.Path)
+	}
+	t.Add(msg)
+	if err := p.client.Edit(ctx, ref.ChannelID, ref.MessageID, notifier.FormatTimeline(t)); err != nil {
+		return notifier.Ref{}, err
+	}
+	if err := p.store.Save(ref, t); err != nil {
+		return notifier.Ref{}, err
+	}
+	return ref, nil
+}
+
+// Reply sends msg as a reply to the referenced message, in the provider's forum topic if set
+func (p *Provider) Reply(ctx context.Context, ref notifier.Ref, msg notifier.Message) (notifier.Ref, error) {
+	payload := RenderReply(ref, msg)
+	payload.MessageThreadID = p.topic
+	msgId, err := p.client.Post(ctx, payload)
+	if err != nil {
+		return notifier.Ref{}, err
+	}
+	return notifier.Ref{ChannelID: ref.ChannelID, MessageID: msgId}, nil
+}
+
+func (p *Provider) Delete(ctx context.Context, ref notifier.Ref) error {
+	if err := p.client.Delete(ctx, ref.ChannelID, ref.MessageID); err != nil {
+		return err
+	}
+	if p.store != nil {
+		return p.store.Remove(ref)
+	}
+	return nil
+}
+
+// Cleanup deletes the messages sent to chatID before the cutoff that are kept in the state file,
+// bots can't read the chat history. Messages Telegram refuses to delete (older than 48 hours
+// or already gone) are dropped from the state file.
+func (p *Provider) Cleanup(ctx context.Context, chatID string, before time.Time) (int, error) {
+	if p.store == nil {
+		return 0, fmt.Errorf("cleanup in Telegram needs a state file: %w", notifier.ErrUnsupported)
+	}
+	timelines, err := p.store.List(chatID)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for ref, t := range timelines {
+		if !t.Sent().Before(before) {
+			continue
+		}
+		if err := p.client.Delete(ctx, ref.ChannelID, ref.MessageID); err != nil {
+			var apiErr *tgbotapi.Error
+			if !errors.As(err, &apiErr) || apiErr.Code != http.StatusBadRequest {
+				return deleted, err
+			}
+			slog.Warn("Telegram message can't be deleted, forgetting it", slog.String("message_id", ref.MessageID), slog.String("error", err.Error()))
+		} else {
+			deleted++
+		}
+		if err := p.store.Remove(ref); err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// Find looks up key in the state file, bots can't search the chat history
+func (p *Provider) Find(ctx context.Context, chatID, key string) (notifier.Ref, bool, error) {
+	if p.store == nil {
+		return notifier.Ref{}, false, fmt.Errorf("finding Telegram messages by key needs a state file: %w", notifier.ErrUnsupported)
+	}
+	return p.store.Find(chatID, key)
+}
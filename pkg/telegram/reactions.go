@@ -0,0 +1,85 @@
+package telegram
+
+import (
+	"cicd-notifier/pkg/notifier"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// emojiNames maps Slack style names to the emoji Telegram accepts as reactions,
+// so the same react inputs work for both channels
+var emojiNames = map[string]string{
+	"+1":           "👍",
+	"thumbsup":     "👍",
+	"-1":           "👎",
+	"thumbsdown":   "👎",
+	"heart":        "❤",
+	"fire":         "🔥",
+	"tada":         "🎉",
+	"eyes":         "👀",
+	"zap":          "⚡",
+	"trophy":       "🏆",
+	"100":          "💯",
+	"pray":         "🙏",
+	"ok_hand":      "👌",
+	"broken_heart": "💔",
+	"thinking":     "🤔",
+	"scream":       "😱",
+	"cry":          "😢",
+	"sunglasses":   "😎",
+	"handshake":    "🤝",
+	"writing_hand": "✍",
+}
+
+// reactionEmoji returns the emoji for a reaction given as emoji or as a name with or without colons
+func reactionEmoji(reaction string) string {
+	if emoji, ok := emojiNames[strings.Trim(reaction, ":")]; ok {
+		return emoji
+	}
+	return reaction
+}
+
+type reactionType struct {
+	Type  string `json:"type"`
+	Emoji string `json:"emoji"`
+}
+
+// SetReaction replaces the bot's reaction on a message, an empty emoji removes it
+func (c *TelegramClient) SetReaction(ctx context.Context, telegramChatId, msgId, emoji string) error {
+	reaction := []reactionType{}
+	if emoji != "" {
+		reaction = append(reaction, reactionType{Type: "emoji", Emoji: emoji})
+	}
+	data, err := json.Marshal(reaction)
+	if err != nil {
+		return err
+	}
+	// tgbotapi predates setMessageReaction
+	params := tgbotapi.Params{"chat_id": telegramChatId, "message_id": msgId, "reaction": string(data)}
+	bot := c.withContext(ctx)
+	err = c.retry.Do(ctx, true, func() error {
+		_, err := bot.MakeRequest("setMessageReaction", params)
+		return retryable(err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed To set Telegram reaction err= %w", err)
+	}
+	return nil
+}
+
+// React sets the bot's reaction on the referenced message. Bots have a single reaction per
+// message, so the last emoji in add replaces the current one, remove only clears it when
+// nothing is added. Telegram accepts a fixed set of emoji, common Slack names are translated.
+func (p *Provider) React(ctx context.Context, ref notifier.Ref, add, remove []string) error {
+	switch {
+	case len(add) > 0:
+		return p.client.SetReaction(ctx, ref.ChannelID, ref.MessageID, reactionEmoji(add[len(add)-1]))
+	case len(remove) > 0:
+		return p.client.SetReaction(ctx, ref.ChannelID, ref.MessageID, "")
+	}
+	return nil
+}
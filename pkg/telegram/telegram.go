@@ -1,9 +1,16 @@
 package telegram
 
 import (
+	"cicd-notifier/pkg/notifier"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -11,15 +18,37 @@ import (
 // Client wraps the telegram bot client
 type TelegramClient struct {
 	*tgbotapi.BotAPI
+	retry notifier.RetryPolicy
+	http  *http.Client
 }
 
 // NewClient creates a new Telegram client with the given token
 func NewClient(token string) (*TelegramClient, error) {
-	bot, err := tgbotapi.NewBotAPI(token)
+	return NewClientWithEndpoint(context.Background(), token, tgbotapi.APIEndpoint, notifier.RetryPolicy{})
+}
+
+// NewClientWithEndpoint creates a Telegram client against apiEndpoint ("https://host/bot%s/%s"),
+// the getMe call made on creation is bound to ctx and retried with policy
+func NewClientWithEndpoint(ctx context.Context, token, apiEndpoint string, policy notifier.RetryPolicy) (*TelegramClient, error) {
+	httpClient := &http.Client{}
+	var bot *tgbotapi.BotAPI
+	err := policy.Do(ctx, true, func() error {
+		var err error
+		bot, err = tgbotapi.NewBotAPIWithClient(token, apiEndpoint, &statusClient{client: httpClient, ctx: ctx})
+		return retryable(err)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return &TelegramClient{BotAPI: bot}, nil
+	return &TelegramClient{BotAPI: bot, retry: policy, http: httpClient}, nil
+}
+
+// withContext returns a copy of the bot whose requests are bound to ctx,
+// tgbotapi builds its requests without a context
+func (c *TelegramClient) withContext(ctx context.Context) *tgbotapi.BotAPI {
+	bot := *c.BotAPI
+	bot.Client = &statusClient{client: c.http, ctx: ctx}
+	return &bot
 }
 
 // InitClient initializes the Telegram client with the provided token
@@ -27,18 +56,133 @@ func InitClient(token string) (*TelegramClient, error) {
 	return NewClient(token)
 }
 
-func (c *TelegramClient) Send(telegramChatId, msg string) (string, error) {
-	intTelegramChatId, err := strconv.ParseInt(telegramChatId, 10, 64)
+// statusError is returned for 5xx responses without a Telegram JSON body
+type statusError struct {
+	code   int
+	status string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("telegram server error: %s", e.status)
+}
+
+// statusClient binds requests to ctx and turns non-JSON 5xx responses (proxies, outages)
+// into a statusError instead of a JSON decoding error
+type statusClient struct {
+	client *http.Client
+	ctx    context.Context
+}
+
+func (c *statusClient) Do(req *http.Request) (*http.Response, error) {
+	if c.ctx != nil {
+		req = req.WithContext(c.ctx)
+	}
+	resp, err := c.client.Do(req)
 	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 500 && !strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		resp.Body.Close()
+		return nil, &statusError{code: resp.StatusCode, status: resp.Status}
+	}
+	return resp, nil
+}
+
+// retryable marks flood control (retry_after), 5xx responses and network failures as retryable
+// Flood-controlled requests were rejected before processing, a 5xx may have sent the message anyway
+func retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.Code == http.StatusTooManyRequests || apiErr.RetryAfter > 0:
+			return &notifier.RetryError{Err: err, RetryAfter: time.Duration(apiErr.RetryAfter) * time.Second, NotProcessed: true}
+		case apiErr.Code >= 500:
+			return &notifier.RetryError{Err: err}
+		}
+		return err
+	}
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return &notifier.RetryError{Err: err}
+	}
+	return notifier.NetworkError(err)
+}
+
+func (c *TelegramClient) Send(ctx context.Context, telegramChatId, msg string) (string, error) {
+	return c.Post(ctx, MessagePayload{ChatID: telegramChatId, Text: msg, ParseMode: parseMode})
+}
+
+// Post sends a rendered payload, sendMessage is called with explicit parameters because
+// tgbotapi has no message_thread_id (forum topics)
+func (c *TelegramClient) Post(ctx context.Context, payload MessagePayload) (string, error) {
+	if _, err := strconv.ParseInt(payload.ChatID, 10, 64); err != nil {
 		slog.Error("Failed to parse telegramChatId to int64", slog.String("error", err.Error()))
 		return "", fmt.Errorf("failed to parse telegramChatId to int64- %s", err.Error())
 	}
-	msgConfig := tgbotapi.NewMessage(intTelegramChatId, msg)
-	msgConfig.ParseMode = "Markdown"
-	tgMsg, err := c.BotAPI.Send(msgConfig)
+	params := tgbotapi.Params{"chat_id": payload.ChatID, "text": payload.Text}
+	params.AddNonEmpty("parse_mode", payload.ParseMode)
+	params.AddNonEmpty("message_thread_id", payload.MessageThreadID)
+	params.AddNonEmpty("reply_to_message_id", payload.ReplyToMessageID)
+	bot := c.withContext(ctx)
+	var tgMsg tgbotapi.Message
+	err := c.retry.Do(ctx, false, func() error {
+		resp, err := bot.MakeRequest("sendMessage", params)
+		if err != nil {
+			return retryable(err)
+		}
+		return json.Unmarshal(resp.Result, &tgMsg)
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed To send Telegram Message err= %s", err)
+		return "", fmt.Errorf("failed To send Telegram Message err= %w", err)
 	}
 	messageIdstr := strconv.Itoa(tgMsg.MessageID)
 	return messageIdstr, nil
 }
+
+// Edit replaces the text of a message sent by the bot
+func (c *TelegramClient) Edit(ctx context.Context, telegramChatId, msgId, msg string) error {
+	intTelegramChatId, err := strconv.ParseInt(telegramChatId, 10, 64)
+	if err != nil {
+		return fmt.Errorf("failed to parse telegramChatId to int64- %s", err.Error())
+	}
+	intMsgId, err := strconv.Atoi(msgId)
+	if err != nil {
+		return fmt.Errorf("failed to parse msgId to int- %s", err.Error())
+	}
+	editConfig := tgbotapi.NewEditMessageText(intTelegramChatId, intMsgId, msg)
+	editConfig.ParseMode = parseMode
+	bot := c.withContext(ctx)
+	err = c.retry.Do(ctx, true, func() error {
+		_, err := bot.Send(editConfig)
+		return retryable(err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed To edit Telegram Message err= %w", err)
+	}
+	return nil
+}
+
+// Delete removes a message sent by the bot
+func (c *TelegramClient) Delete(ctx context.Context, telegramChatId, msgId string) error {
+	intTelegramChatId, err := strconv.ParseInt(telegramChatId, 10, 64)
+	if err != nil {
+		slog.Error("Failed to parse telegramChatId to int64", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to parse telegramChatId to int64- %s", err.Error())
+	}
+	intMsgId, err := strconv.Atoi(msgId)
+	if err != nil {
+		return fmt.Errorf("failed to parse msgId to int- %s", err.Error())
+	}
+	bot := c.withContext(ctx)
+	err = c.retry.Do(ctx, true, func() error {
+		_, err := bot.Request(tgbotapi.NewDeleteMessage(intTelegramChatId, intMsgId))
+		return retryable(err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed To delete Telegram Message err= %w", err)
+	}
+	return nil
+}
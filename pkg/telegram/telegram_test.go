@@ -0,0 +1,272 @@
+package telegram
+
+import (
+	"cicd-notifier/pkg/notifier"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testRetry = notifier.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Second}
+
+const getMeResponse = `{"ok":true,"result":{"id":1,"is_bot":true,"first_name":"bot","username":"test_bot"}}`
+
+// newTestProvider serves the Bot API from handler, getMe always succeeds
+func newTestProvider(t *testing.T, handler func(calls int32, w http.ResponseWriter)) (*Provider, *int32) {
+	t.Helper()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/getMe") {
+			w.Write([]byte(getMeResponse))
+			return
+		}
+		handler(atomic.AddInt32(&calls, 1), w)
+	}))
+	t.Cleanup(srv.Close)
+	p, err := NewProvider(context.Background(), "123:abc", WithRetry(testRetry), WithAPIEndpoint(srv.URL+"/bot%s/%s"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, &calls
+}
+
+func TestSendRetriesFloodControl(t *testing.T) {
+	p, calls := newTestProvider(t, func(n int32, w http.ResponseWriter) {
+		if n == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 0","parameters":{"retry_after":0}}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"result":{"message_id":42,"date":0,"chat":{"id":-100}}}`))
+	})
+	ref, err := p.Send(context.Background(), "-100", notifier.Message{Text: "hi"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if ref.MessageID != "42" {
+		t.Errorf("MessageID = %q, want 42", ref.MessageID)
+	}
+	if atomic.LoadInt32(calls) != 2 {
+		t.Errorf("sendMessage calls = %d, want 2", atomic.LoadInt32(calls))
+	}
+}
+
+func TestSendDoesNotRetryServerError(t *testing.T) {
+	p, calls := newTestProvider(t, func(n int32, w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	if _, err := p.Send(context.Background(), "-100", notifier.Message{Text: "hi"}); err == nil {
+		t.Fatal("Send() error = nil, want error")
+	}
+	if atomic.LoadInt32(calls) != 1 {
+		t.Errorf("sendMessage calls = %d, want 1", atomic.LoadInt32(calls))
+	}
+}
+
+func TestDeleteRetriesServerError(t *testing.T) {
+	p, calls := newTestProvider(t, func(n int32, w http.ResponseWriter) {
+		if n == 1 {
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"ok":true,"result":true}`))
+	})
+	if err := p.Delete(context.Background(), notifier.Ref{ChannelID: "-100", MessageID: "42"}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if atomic.LoadInt32(calls) != 2 {
+		t.Errorf("deleteMessage calls = %d, want 2", atomic.LoadInt32(calls))
+	}
+}
+
+func TestUpdateEditsTimeline(t *testing.T) {
+	var edited url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/getMe"):
+			w.Write([]byte(getMeResponse))
+		case strings.HasSuffix(r.URL.Path, "/sendMessage"):
+			w.Write([]byte(`{"ok":true,"result":{"message_id":42,"date":0,"chat":{"id":-100}}}`))
+		case strings.HasSuffix(r.URL.Path, "/editMessageText"):
+			r.ParseForm()
+			edited = r.PostForm
+			w.Write([]byte(`{"ok":true,"result":{"message_id":42,"date":0,"chat":{"id":-100}}}`))
+		}
+	}))
+	defer srv.Close()
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	p, err := NewProvider(context.Background(), "123:abc", WithAPIEndpoint(srv.URL+"/bot%s/%s"), WithStateFile(stateFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	first := notifier.Message{Text: "Deploy started", Commit: &notifier.CommitInfo{CommitSha: "abc123"}, Time: start}
+	ref, err := p.Send(context.Background(), "-100", first)
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	second := notifier.Message{Text: "Deployed", Time: start.Add(time.Minute)}
+	if _, err := p.Update(context.Background(), ref, second); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if edited.Get("message_id") != "42" {
+		t.Errorf("edited message_id = %q, want 42", edited.Get("message_id"))
+	}
+	expected := notifier.FormatText(first) + notifier.FormatLine(second)
+	if got := edited.Get("text"); got != expected {
+		t.Errorf("edited text = %q, want %q", got, expected)
+	}
+
+	if _, err := p.Update(context.Background(), notifier.Ref{ChannelID: "-100", MessageID: "7"}, second); err == nil {
+		t.Error("Update() of a message without timeline error = nil")
+	}
+}
+
+func TestReplyPostsInTopic(t *testing.T) {
+	var posted url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/getMe") {
+			w.Write([]byte(getMeResponse))
+			return
+		}
+		r.ParseForm()
+		posted = r.PostForm
+		w.Write([]byte(`{"ok":true,"result":{"message_id":43,"date":0,"chat":{"id":-100}}}`))
+	}))
+	defer srv.Close()
+	p, err := NewProvider(context.Background(), "123:abc", WithAPIEndpoint(srv.URL+"/bot%s/%s"), WithTopic("5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref, err := p.Reply(context.Background(), notifier.Ref{ChannelID: "-100", MessageID: "42"}, notifier.Message{Text: "Tests failed"})
+	if err != nil {
+		t.Fatalf("Reply() error = %v", err)
+	}
+	if ref.MessageID != "43" {
+		t.Errorf("Reply() ref = %+v", ref)
+	}
+	if posted.Get("reply_to_message_id") != "42" || posted.Get("message_thread_id") != "5" {
+		t.Errorf("posted reply_to_message_id = %q, message_thread_id = %q", posted.Get("reply_to_message_id"), posted.Get("message_thread_id"))
+	}
+}
+
+func TestCleanupDeletesStoredMessages(t *testing.T) {
+	var deleted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/getMe") {
+			w.Write([]byte(getMeResponse))
+			return
+		}
+		r.ParseForm()
+		deleted = append(deleted, r.PostForm.Get("message_id"))
+		if r.PostForm.Get("message_id") == "2" {
+			w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: message can't be deleted"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"result":true}`))
+	}))
+	defer srv.Close()
+	store := notifier.FileStore{Path: filepath.Join(t.TempDir(), "state.json")}
+	p, err := NewProvider(context.Background(), "123:abc", WithAPIEndpoint(srv.URL+"/bot%s/%s"), WithStateFile(store.Path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2023, 1, 2, 12, 0, 0, 0, time.UTC)
+	for id, age := range map[string]time.Duration{"1": 25 * time.Hour, "2": 72 * time.Hour, "3": time.Hour} {
+		ref := notifier.Ref{ChannelID: "-100", MessageID: id}
+		if err := store.Save(ref, notifier.NewTimeline(notifier.Message{Text: "Deploy", Time: now.Add(-age)})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.Save(notifier.Ref{ChannelID: "-200", MessageID: "4"}, notifier.NewTimeline(notifier.Message{Text: "Deploy", Time: now.Add(-72 * time.Hour)}))
+
+	count, err := p.Cleanup(context.Background(), "-100", now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	if count != 1 || len(deleted) != 2 {
+		t.Errorf("Cleanup() = %d, deleteMessage calls %v, want 1 of 2", count, deleted)
+	}
+	left, err := store.List("-100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := left[notifier.Ref{ChannelID: "-100", MessageID: "3"}]; len(left) != 1 || !ok {
+		t.Errorf("state file after cleanup = %v, want only message 3", left)
+	}
+}
+
+func TestReactSetsReaction(t *testing.T) {
+	var reactions []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/getMe") {
+			w.Write([]byte(getMeResponse))
+			return
+		}
+		r.ParseForm()
+		reactions = append(reactions, r.PostForm.Get("reaction"))
+		w.Write([]byte(`{"ok":true,"result":true}`))
+	}))
+	defer srv.Close()
+	p, err := NewProvider(context.Background(), "123:abc", WithAPIEndpoint(srv.URL+"/bot%s/%s"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref := notifier.Ref{ChannelID: "-100", MessageID: "42"}
+
+	if err := p.React(context.Background(), ref, []string{":thumbsup:"}, []string{"eyes"}); err != nil {
+		t.Fatalf("React() error = %v", err)
+	}
+	if err := p.React(context.Background(), ref, nil, []string{"thumbsup"}); err != nil {
+		t.Fatalf("React() error = %v", err)
+	}
+	expected := []string{`[{"type":"emoji","emoji":"👍"}]`, `[]`}
+	if strings.Join(reactions, ",") != strings.Join(expected, ",") {
+		t.Errorf("reactions = %v, want %v", reactions, expected)
+	}
+}
+
+func TestPinUnpinsPreviousOwnPin(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		r.ParseForm()
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/getMe"):
+			w.Write([]byte(getMeResponse))
+		case strings.HasSuffix(r.URL.Path, "/getChat"):
+			w.Write([]byte(`{"ok":true,"result":{"id":-100,"type":"supergroup","pinned_message":{"message_id":41,"date":0,"chat":{"id":-100},"from":{"id":1,"is_bot":true}}}}`))
+		default:
+			requests = append(requests, r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]+" "+r.PostForm.Get("message_id"))
+			w.Write([]byte(`{"ok":true,"result":true}`))
+		}
+	}))
+	defer srv.Close()
+	p, err := NewProvider(context.Background(), "123:abc", WithAPIEndpoint(srv.URL+"/bot%s/%s"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Pin(context.Background(), notifier.Ref{ChannelID: "-100", MessageID: "42"}, true); err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+	expected := []string{"pinChatMessage 42", "unpinChatMessage 41"}
+	if strings.Join(requests, ",") != strings.Join(expected, ",") {
+		t.Errorf("requests = %v, want %v", requests, expected)
+	}
+}
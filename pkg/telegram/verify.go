@@ -0,0 +1,74 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Chat returns the chat, retrying transient failures
+func (c *TelegramClient) Chat(ctx context.Context, telegramChatId string) (tgbotapi.Chat, error) {
+	intTelegramChatId, err := strconv.ParseInt(telegramChatId, 10, 64)
+	if err != nil {
+		return tgbotapi.Chat{}, fmt.Errorf("failed to parse telegramChatId to int64- %s", err.Error())
+	}
+	bot := c.withContext(ctx)
+	var chat tgbotapi.Chat
+	err = c.retry.Do(ctx, true, func() error {
+		var err error
+		chat, err = bot.GetChat(tgbotapi.ChatInfoConfig{ChatConfig: tgbotapi.ChatConfig{ChatID: intTelegramChatId}})
+		return retryable(err)
+	})
+	if err != nil {
+		return tgbotapi.Chat{}, fmt.Errorf("failed to get Telegram chat- %w", err)
+	}
+	return chat, nil
+}
+
+// ChatAccess returns the chat and the bot's membership in it, retrying transient failures
+func (c *TelegramClient) ChatAccess(ctx context.Context, telegramChatId string) (tgbotapi.Chat, tgbotapi.ChatMember, error) {
+	chat, err := c.Chat(ctx, telegramChatId)
+	if err != nil {
+		return tgbotapi.Chat{}, tgbotapi.ChatMember{}, err
+	}
+	intTelegramChatId := chat.ID
+	bot := c.withContext(ctx)
+	var member tgbotapi.ChatMember
+	err = c.retry.Do(ctx, true, func() error {
+		var err error
+		member, err = bot.GetChatMember(tgbotapi.GetChatMemberConfig{
+			ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: intTelegramChatId, UserID: c.Self.ID},
+		})
+		return retryable(err)
+	})
+	if err != nil {
+		return chat, tgbotapi.ChatMember{}, fmt.Errorf("failed to get Telegram chat member- %w", err)
+	}
+	return chat, member, nil
+}
+
+// Verify reports the bot from getMe (an invalid token already failed in NewProvider),
+// then checks with getChat/getChatMember that the bot can post to chatID
+func (p *Provider) Verify(ctx context.Context, chatID string) (string, error) {
+	report := fmt.Sprintf("Telegram credentials OK: @%s", p.client.Self.UserName)
+	if chatID == "" {
+		return report, nil
+	}
+	chat, member, err := p.client.ChatAccess(ctx, chatID)
+	if err != nil {
+		return "", fmt.Errorf("telegram verification failed: cannot access chat %s, add the bot to it- %w", chatID, err)
+	}
+	name := chat.Title
+	if name == "" {
+		name = chat.UserName
+	}
+	switch {
+	case member.HasLeft() || member.WasKicked():
+		return "", fmt.Errorf("telegram verification failed: bot is not a member of %q (%s)", name, member.Status)
+	case chat.IsChannel() && !member.IsCreator() && !(member.IsAdministrator() && member.CanPostMessages):
+		return "", fmt.Errorf("telegram verification failed: bot needs to be an administrator with \"Post messages\" in channel %q", name)
+	}
+	return fmt.Sprintf("%s\nChat OK: %q (%s), bot is %s", report, name, chat.Type, member.Status), nil
+}
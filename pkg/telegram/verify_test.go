@@ -0,0 +1,77 @@
+package telegram
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name     string
+		chat     string
+		member   string
+		expected string
+		wantErr  string
+	}{
+		{
+			name:     "member of a group",
+			chat:     `{"id":-100,"type":"supergroup","title":"Deploys"}`,
+			member:   `{"user":{"id":1},"status":"member"}`,
+			expected: "Telegram credentials OK: @test_bot\nChat OK: \"Deploys\" (supergroup), bot is member",
+		},
+		{
+			name:    "removed from the group",
+			chat:    `{"id":-100,"type":"supergroup","title":"Deploys"}`,
+			member:  `{"user":{"id":1},"status":"left"}`,
+			wantErr: `bot is not a member of "Deploys" (left)`,
+		},
+		{
+			name:    "channel without post rights",
+			chat:    `{"id":-100,"type":"channel","title":"Releases"}`,
+			member:  `{"user":{"id":1},"status":"administrator","can_post_messages":false}`,
+			wantErr: `needs to be an administrator with "Post messages" in channel "Releases"`,
+		},
+		{
+			name:     "channel administrator",
+			chat:     `{"id":-100,"type":"channel","title":"Releases"}`,
+			member:   `{"user":{"id":1},"status":"administrator","can_post_messages":true}`,
+			expected: "bot is administrator",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case strings.HasSuffix(r.URL.Path, "/getMe"):
+					w.Write([]byte(getMeResponse))
+				case strings.HasSuffix(r.URL.Path, "/getChat"):
+					w.Write([]byte(`{"ok":true,"result":` + tt.chat + `}`))
+				case strings.HasSuffix(r.URL.Path, "/getChatMember"):
+					w.Write([]byte(`{"ok":true,"result":` + tt.member + `}`))
+				}
+			}))
+			defer srv.Close()
+			p, err := NewProvider(context.Background(), "123:abc", WithAPIEndpoint(srv.URL+"/bot%s/%s"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			report, err := p.Verify(context.Background(), "-100")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Verify() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if !strings.Contains(report, tt.expected) {
+				t.Errorf("Verify() = %q, want it to contain %q", report, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"cicd-notifier/pkg/notifier"
+	"cicd-notifier/pkg/slack"
+	"cicd-notifier/pkg/telegram"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// newProvider returns the provider for cfg.Channel, replaced with fakes in tests
+var newProvider = func(ctx context.Context, cfg ActionInputs) (notifier.Provider, error) {
+	retry := retryPolicy(cfg)
+	switch strings.ToLower(cfg.Channel) {
+	case "slack":
+		p, err := slack.NewProvider(cfg.ApiKey, slack.WithBlocks(cfg.SlackBlocks), slack.WithRetry(retry),
+			slack.WithReplyBroadcast(cfg.ReplyBroadcast), slack.WithConcurrentUpdates(cfg.Action == "upsert" || cfg.Action == "aggregate"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize slack client- %w", err)
+		}
+		return p, nil
+	case "telegram":
+		p, err := telegram.NewProvider(ctx, cfg.ApiKey, telegram.WithRetry(retry), telegram.WithStateFile(cfg.StateFile), telegram.WithTopic(cfg.TopicID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize telegram client- %w", err)
+		}
+		return p, nil
+	}
+	return nil, fmt.Errorf("%w: unsupported channel %q", errInvalidConfig, cfg.Channel)
+}
+
+// retryPolicy builds the provider retry policy from the retries/retry_delay inputs
+func retryPolicy(cfg ActionInputs) notifier.RetryPolicy {
+	policy := notifier.DefaultRetryPolicy
+	policy.MaxAttempts = cfg.Retries
+	policy.BaseDelay = cfg.RetryDelay
+	return policy
+}
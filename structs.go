@@ -1,20 +1,59 @@
 package main
 
+import "time"
+
 // ActionInputs represents the input parameters for the notification action
 type ActionInputs struct {
-	Action        string // Required: Send/Update
-	Channel       string // Required: Channel (Telegram/Slack)
-	Message       string // Required: Message to send
-	ApiKey        string // Required: API key
-	ChannelId     string // Required: channel/chat used in slack/telegram
-	MsgID         string // Optional: ID of the message to update
-	AddCommitInfo bool   // Optional: Whether to add commit info
-	ImageTag      string // Optional: Docker image tag
-	CommitSha     string // Optional: Commit SHA
-	Branch        string // Optional: Branch name
-	Author        string // Optional: Commit author
-	CommitTime    string // Optional: Commit time
-	CommitMsg     string // Optional: Commit Message
-	WorkflowName  string // Optional: WorkflowName
-	TimeZone      string // Optional: Timezone for messages
+	Action         string        // Required: Send/Update
+	Channel        string        // Required: Channel (Telegram/Slack)
+	Message        string        // Required: Message to send
+	ApiKey         string        // Required: API key
+	ChannelId      string        // Required: channel/chat used in slack/telegram
+	MsgID          string        // Optional: ID of the message to update
+	Key            string        // Optional: Correlation key the message is sent with and upsert finds it by
+	StepName       string        // Optional: Named step the message starts/completes
+	UpdateMode     string        // Optional: How update changes the message: edit (default) or thread
+	ReplyBroadcast bool          // Optional: Also show Slack thread replies in the channel
+	TopicID        string        // Optional: Telegram forum topic (message_thread_id)
+	Status         string        // Optional: Step status (pending/running/success/failure/cancelled/skipped)
+	Duration       time.Duration // Optional: Duration reported by a matrix leg
+	StepURL        string        // Optional: Link to the job of a matrix leg, defaults to the run
+	MatrixTotal    int           // Optional: Number of matrix legs, the matrix is running until all reported
+	AddCommitInfo  bool          // Optional: Whether to add commit info
+	ImageTag       string        // Optional: Docker image tag
+	CommitSha      string        // Optional: Commit SHA
+	Branch         string        // Optional: Branch name
+	Author         string        // Optional: Commit author
+	AuthorEmail    string        // Discovered: Commit author email
+	Pusher         string        // Discovered: Who pushed/triggered the run
+	CommitTime     string        // Optional: Commit time
+	CommitMsg      string        // Optional: Commit Message
+	WorkflowName   string        // Optional: WorkflowName
+	TimeZone       string        // Optional: Timezone for messages
+	SlackBlocks    bool          // Optional: Render Slack messages with Block Kit
+	DotenvFile     string        // Optional: dotenv report/state file written outside GitHub Actions
+	StateFile      string        // Optional: JSON file keeping message timelines for Telegram updates
+	Retries        int           // Optional: Total attempts per API call, 1 disables retries
+	RetryDelay     time.Duration // Optional: First backoff delay, doubled on every retry
+	Timeout        time.Duration // Optional: Overall deadline for the API calls, 0 disables it
+	ReactAdd       string        // Optional: Comma separated emoji the react action adds
+	ReactRemove    string        // Optional: Comma separated emoji the react action removes
+	UnpinPrevious  bool          // Optional: pin unpins the bot's previously pinned message (default true)
+	OlderThan      time.Duration // Optional: Minimum age of the messages cleanup deletes
+	FailOnError    bool          // Optional: Fail the step when the notification fails (default true)
+	DryRun         bool          // Optional: Print the API payloads instead of sending them
+	AutoFinalize   bool          // Optional: Send a started message in pre and finalize it with the job status in post
+	GithubToken    string        // Optional: Token used to read the job status in post
+	RunURL         string        // Optional: Link to the workflow run
+	CommitURL      string        // Optional: Link to the commit
+	CompareURL     string        // Optional: Link to the compare view
+	Repository     string        // Discovered: owner/repo
+	RunID          string        // Discovered: workflow run ID
+	CISystem       string        // Discovered: CI system named in the message header
+	RunAttempt     string        // Discovered: workflow run attempt
+	PRNumber       string        // Discovered: Pull request number
+	PRTitle        string        // Discovered: Pull request title
+	PRURL          string        // Discovered: Pull request link
+	ReleaseTag     string        // Discovered: Release tag
+	ReleaseURL     string        // Discovered: Release link
 }